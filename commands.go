@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"os"
+	"stealth/hd"
+	"stealth/indexer"
+	"stealth/registry"
+	"stealth/scanner"
+	"stealth/sender"
+	"stealth/signer"
+	"stealth/stealthaddr"
+	"stealth/userop"
+	"stealth/vectors"
+	"strings"
+)
+
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "", "write the keys to this encrypted keystore instead of printing them")
+	passwordFile := fs.String("password-file", "", "file holding the passphrase of the -out keystore")
+	mnemonicFile := fs.String("mnemonic-file", "", "derive the keys from the BIP-39 mnemonic in this file instead of generating them")
+	index := fs.Uint("index", 0, "account index to derive from -mnemonic-file")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	var account *stealthaddr.Account
+	var err error
+	if *mnemonicFile != "" {
+		var mnemonic []byte
+		if mnemonic, err = os.ReadFile(*mnemonicFile); err != nil {
+			return err
+		}
+		account, err = hd.AccountFromMnemonic(strings.TrimSpace(string(mnemonic)), uint32(*index))
+	} else {
+		account, err = stealthaddr.GenerateAccount()
+	}
+	if err != nil {
+		return err
+	}
+	var r record
+	if *out != "" {
+		if err := writeKeyFile(*out, *passwordFile, account); err != nil {
+			return err
+		}
+	} else {
+		r = append(r,
+			field{"spendingKey", "spending key", hexutil.Bytes(math.PaddedBigBytes(account.SpendingKey, 32))},
+			field{"viewingKey", "viewing key", hexutil.Bytes(math.PaddedBigBytes(account.ViewingKey, 32))})
+	}
+	return results.write(append(r, field{"metaAddress", "meta-address", account.MetaAddress().String()}))
+}
+
+func runMnemonic(args []string) error {
+	fs := flag.NewFlagSet("mnemonic", flag.ExitOnError)
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	mnemonic, err := hd.NewMnemonic()
+	if err != nil {
+		return err
+	}
+	if results.text() {
+		// Just the words, so that the output can be redirected into a -mnemonic-file.
+		fmt.Println(mnemonic)
+		return nil
+	}
+	return results.write(record{{"mnemonic", "mnemonic", mnemonic}})
+}
+
+func runDerive(args []string) error {
+	fs := flag.NewFlagSet("derive", flag.ExitOnError)
+	metaFlag := fs.String("meta", "", "recipient meta-address (st:eth:0x...)")
+	ephemeral := fs.String("ephemeral", "", "ephemeral private key r (hex), for reproducible output; a fresh one is generated by default")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	meta, err := stealthaddr.ParseMetaAddress(*metaFlag)
+	if err != nil {
+		return err
+	}
+	r, err := ephemeralKey(*ephemeral)
+	if err != nil {
+		return err
+	}
+	payment, err := stealthaddr.ComputeStealthAddress(meta, r)
+	if err != nil {
+		return err
+	}
+	announcement, err := stealthaddr.BuildAnnouncement(payment, nil)
+	if err != nil {
+		return err
+	}
+	calldata, err := stealthaddr.EncodeAnnounceCalldata(announcement)
+	if err != nil {
+		return err
+	}
+	return results.write(record{
+		{"stealthAddress", "stealth address", payment.Address.Hex()},
+		{"ephemeralPublicKey", "ephemeral public key", hexutil.Bytes(payment.EphemeralPublicKey.Bytes())},
+		{"viewTag", "view tag", hexutil.Bytes{payment.ViewTag}},
+		{"announceCalldata", "announce calldata", hexutil.Bytes(calldata)},
+	})
+}
+
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	rpc := fs.String("rpc", "", "Ethereum node RPC endpoint")
+	keyFlag := fs.String("key", "", "private key of the paying account (hex)")
+	clef := fs.String("clef", "", "sign with this clef endpoint (IPC path or URL) instead of -key")
+	fromFlag := fs.String("from", "", "paying account in clef, with -clef")
+	metaFlag := fs.String("meta", "", "recipient meta-address (st:eth:0x...)")
+	amountFlag := fs.String("amount", "", "amount to send, in wei or token units")
+	token := fs.String("token", "", "ERC-20 or ERC-721 token contract to send instead of ETH")
+	tokenID := fs.String("token-id", "", "ERC-721 token id to send, instead of -amount")
+	announcer := fs.String("announcer", stealthaddr.AnnouncerAddress.Hex(), "ERC5564Announcer contract address")
+	memo := fs.String("memo", "", "short note to encrypt to the recipient in the announcement, such as an invoice id")
+	smartAccount := fs.Bool("smart-account", false, "pay the ERC-4337 SimpleAccount of the stealth address, for sweep-sponsored")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	meta, err := stealthaddr.ParseMetaAddress(*metaFlag)
+	if err != nil {
+		return err
+	}
+	transfer, err := transferFlags(*amountFlag, *token, *tokenID)
+	if err != nil {
+		return err
+	}
+	payer, err := payingSigner(*keyFlag, *clef, *fromFlag)
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.Dial(*rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	s := sender.NewWithSigner(client, payer, common.HexToAddress(*announcer))
+	if *smartAccount {
+		s.PayTo = func(ctx context.Context, owner common.Address) (common.Address, error) {
+			return userop.AccountAddress(ctx, client, userop.SimpleAccountFactory, owner, new(big.Int))
+		}
+	}
+	payment, err := s.SendTransfer(context.Background(), meta, transfer, []byte(*memo))
+	if err != nil {
+		return err
+	}
+	r := record{{"stealthAddress", "stealth address", payment.Stealth.Address.Hex()}}
+	if *smartAccount {
+		r = append(r, field{"paidTo", "paid to", payment.PaidTo.Hex()})
+	}
+	return results.write(append(r,
+		field{"transferTx", "transfer tx", payment.Transfer.Hash().Hex()},
+		field{"announceTx", "announce tx", payment.Announce.Hash().Hex()}))
+}
+
+func runScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	var keys keyFlags
+	keys.register(fs, true)
+	rpc := fs.String("rpc", "", "Ethereum node RPC endpoint")
+	from := fs.Int64("from", 0, "first block to scan")
+	to := fs.Int64("to", -1, "last block to scan (default: latest)")
+	announcer := fs.String("announcer", stealthaddr.AnnouncerAddress.Hex(), "ERC5564Announcer contract address")
+	dbPath := fs.String("db", "", "announcement index directory: sync it from -index-from, then scan it instead of the node")
+	indexFrom := fs.Uint64("index-from", 0, "first block of the -db index, usually the announcer's deployment block; fixed when the index is created")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	watchOnly, err := keys.watchOnly()
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.Dial(*rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var toBlock *big.Int
+	if *to >= 0 {
+		toBlock = big.NewInt(*to)
+	}
+	var logs scanner.Backend = client
+	if *dbPath != "" {
+		db, err := indexer.OpenLevelDB(*dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		idx := indexer.New(client, db, common.HexToAddress(*announcer), *indexFrom)
+		synced, err := idx.Sync(context.Background())
+		if err != nil {
+			return err
+		}
+		results.info("index synced to block %d\n", synced)
+		logs = idx
+	}
+	s := scanner.NewWatchOnly(logs, common.HexToAddress(*announcer), watchOnly)
+	matches, err := s.Backfill(context.Background(), big.NewInt(*from), toBlock)
+	if err != nil {
+		return err
+	}
+	if !results.text() {
+		rows := make([]record, len(matches))
+		for i, m := range matches {
+			rows[i] = matchRecord(m)
+		}
+		return results.writeList(matchRecord(scanner.Match{}), rows)
+	}
+	for _, m := range matches {
+		fmt.Printf("block %d tx %s: stealth address %s ephemeral public key %x%s\n",
+			m.Log.BlockNumber, m.Log.TxHash, m.StealthAddress, m.EphemeralPubKey.Bytes(), describeTransfer(m.Transfer))
+		if m.Memo != nil {
+			fmt.Printf("  memo: %q\n", m.Memo)
+		}
+	}
+	results.info("%d matches\n", len(matches))
+	return nil
+}
+
+func runRevealKey(args []string) error {
+	fs := flag.NewFlagSet("revealkey", flag.ExitOnError)
+	var keys keyFlags
+	keys.register(fs, false)
+	ephemeral := fs.String("ephemeral-pub", "", "announced ephemeral public key R (compressed hex)")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	account, err := keys.account()
+	if err != nil {
+		return err
+	}
+	b, err := hexutil.Decode(ensure0x(*ephemeral))
+	if err != nil {
+		return err
+	}
+	R, err := stealthaddr.ParsePoint(b)
+	if err != nil {
+		return err
+	}
+	key, err := account.StealthKey(R)
+	if err != nil {
+		return err
+	}
+	return results.write(record{
+		{"stealthAddress", "stealth address", crypto.PubkeyToAddress(key.PublicKey).Hex()},
+		{"stealthPrivateKey", "stealth private key", hexutil.Bytes(crypto.FromECDSA(key))},
+	})
+}
+
+func runSweepSponsored(args []string) error {
+	fs := flag.NewFlagSet("sweep-sponsored", flag.ExitOnError)
+	var keys keyFlags
+	keys.register(fs, false)
+	ephemeral := fs.String("ephemeral-pub", "", "announced ephemeral public key R (compressed hex)")
+	rpc := fs.String("rpc", "", "Ethereum node RPC endpoint")
+	bundlerURL := fs.String("bundler", "", "ERC-4337 bundler RPC endpoint")
+	paymasterURL := fs.String("paymaster", "", "pm_sponsorUserOperation endpoint that pays the gas (default: the account pays)")
+	token := fs.String("token", "", "ERC-20 token to sweep instead of ETH")
+	toFlag := fs.String("to", "", "address to sweep to")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	if !common.IsHexAddress(*toFlag) {
+		return errors.New("-to must be an address")
+	}
+	account, err := keys.account()
+	if err != nil {
+		return err
+	}
+	b, err := hexutil.Decode(ensure0x(*ephemeral))
+	if err != nil {
+		return err
+	}
+	R, err := stealthaddr.ParsePoint(b)
+	if err != nil {
+		return err
+	}
+	owner, err := signer.NewStealthSigner(account, R)
+	if err != nil {
+		return err
+	}
+	defer owner.Close()
+
+	client, err := ethclient.Dial(*rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	bundler, err := userop.DialBundler(*bundlerURL)
+	if err != nil {
+		return err
+	}
+	defer bundler.Close()
+	var paymaster userop.Paymaster
+	if *paymasterURL != "" {
+		pm, err := gethrpc.Dial(*paymasterURL)
+		if err != nil {
+			return err
+		}
+		defer pm.Close()
+		paymaster = userop.NewRPCPaymaster(pm)
+	}
+
+	ctx := context.Background()
+	sweeper := userop.NewSweeper(client, bundler, paymaster)
+	var hash common.Hash
+	if *token != "" {
+		hash, err = sweeper.SweepToken(ctx, owner, common.HexToAddress(*token), common.HexToAddress(*toFlag))
+	} else {
+		hash, err = sweeper.SweepETH(ctx, owner, common.HexToAddress(*toFlag))
+	}
+	if err != nil {
+		return err
+	}
+	results.info("user operation %s submitted, waiting for it to be included\n", hash)
+	receipt, err := bundler.Wait(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if !receipt.Success {
+		return fmt.Errorf("user operation reverted: %s", receipt.Reason)
+	}
+	return results.write(record{
+		{"userOperation", "user operation", hash.Hex()},
+		{"transaction", "included in tx", receipt.Receipt.TransactionHash.Hex()},
+	})
+}
+
+func runVerifyVectors(args []string) error {
+	fs := flag.NewFlagSet("verify-vectors", flag.ExitOnError)
+	file := fs.String("file", "", "JSON file of vectors to check instead of the built-in ones")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	set, err := vectors.Embedded()
+	if *file != "" {
+		var data []byte
+		if data, err = os.ReadFile(*file); err != nil {
+			return err
+		}
+		set, err = vectors.Parse(data)
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	var rows []record
+	for _, v := range set.Vectors {
+		mismatches := v.Verify()
+		if len(mismatches) > 0 {
+			failed++
+		}
+		rows = append(rows, vectorRecord(v.Name, mismatches))
+		if !results.text() {
+			continue
+		}
+		if len(mismatches) == 0 {
+			fmt.Printf("ok   %s\n", v.Name)
+			continue
+		}
+		fmt.Printf("FAIL %s\n", v.Name)
+		for _, m := range mismatches {
+			fmt.Printf("     %s: got %s, want %s\n", m.Field, m.Got, m.Want)
+		}
+	}
+	if !results.text() {
+		if err := results.writeList(vectorRecord("", nil), rows); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d vectors failed", failed, len(set.Vectors))
+	}
+	results.info("all %d vectors passed (%s)\n", len(set.Vectors), set.Source)
+	return nil
+}
+
+func runRegister(args []string) error {
+	fs := flag.NewFlagSet("register", flag.ExitOnError)
+	rpc := fs.String("rpc", "", "Ethereum node RPC endpoint")
+	keyFlag := fs.String("key", "", "private key of the registering account (hex)")
+	metaFlag := fs.String("meta", "", "meta-address to register (st:eth:0x...)")
+	registryFlag := fs.String("registry", registry.Address.Hex(), "ERC-6538 registry contract address")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	meta, err := stealthaddr.ParseMetaAddress(*metaFlag)
+	if err != nil {
+		return err
+	}
+	key, err := parseECDSAKey(*keyFlag)
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.Dial(*rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	r := registry.New(client, common.HexToAddress(*registryFlag))
+	tx, err := r.RegisterMetaAddress(context.Background(), key, big.NewInt(stealthaddr.SchemeIDSecp256k1), meta)
+	if err != nil {
+		return err
+	}
+	return results.write(record{{"registerTx", "register tx", tx.Hash().Hex()}})
+}
+
+func runLookup(args []string) error {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	rpc := fs.String("rpc", "", "Ethereum node RPC endpoint")
+	account := fs.String("account", "", "address of the registrant")
+	registryFlag := fs.String("registry", registry.Address.Hex(), "ERC-6538 registry contract address")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	if !common.IsHexAddress(*account) {
+		return errors.New("-account must be an address")
+	}
+	client, err := ethclient.Dial(*rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	r := registry.New(client, common.HexToAddress(*registryFlag))
+	meta, err := r.LookupMetaAddress(context.Background(), common.HexToAddress(*account), big.NewInt(stealthaddr.SchemeIDSecp256k1))
+	if err != nil {
+		return err
+	}
+	return results.write(record{{"metaAddress", "meta-address", meta.String()}})
+}
+
+func runExportWatchOnly(args []string) error {
+	fs := flag.NewFlagSet("export-watchonly", flag.ExitOnError)
+	var keys keyFlags
+	keys.register(fs, false)
+	out := fs.String("out", "", "file to write the viewing key and spending public key to")
+	results := outputFlags(fs)
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("need -out")
+	}
+	account, err := keys.account()
+	if err != nil {
+		return err
+	}
+	if err := writeWatchOnlyFile(*out, account.WatchOnly()); err != nil {
+		return err
+	}
+	return results.write(record{{"metaAddress", "meta-address", account.MetaAddress().String()}})
+}
+
+// payingSigner returns the signer of the paying account: clef if endpoint is set, the
+// private key otherwise.
+func payingSigner(key, endpoint, from string) (signer.Signer, error) {
+	if endpoint != "" {
+		if !common.IsHexAddress(from) {
+			return nil, errors.New("-clef needs -from with the paying address")
+		}
+		return signer.NewClefSigner(endpoint, common.HexToAddress(from))
+	}
+	k, err := parseECDSAKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewKeySigner(k), nil
+}
+
+// transferFlags returns the transfer send makes: amount wei of ETH, amount units of the
+// ERC-20 token, or the ERC-721 token tokenID.
+func transferFlags(amount, token, tokenID string) (*stealthaddr.Transfer, error) {
+	if tokenID != "" {
+		if token == "" || amount != "" {
+			return nil, errors.New("-token-id needs -token and no -amount")
+		}
+		id, ok := new(big.Int).SetString(tokenID, 10)
+		if !ok || id.Sign() < 0 {
+			return nil, errors.New("-token-id must be a token id")
+		}
+		if !common.IsHexAddress(token) {
+			return nil, errors.New("-token must be an address")
+		}
+		return stealthaddr.ERC721Transfer(common.HexToAddress(token), id), nil
+	}
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() <= 0 {
+		return nil, errors.New("-amount must be a positive number")
+	}
+	if token == "" {
+		return stealthaddr.ETHTransfer(value), nil
+	}
+	if !common.IsHexAddress(token) {
+		return nil, errors.New("-token must be an address")
+	}
+	return stealthaddr.ERC20Transfer(common.HexToAddress(token), value), nil
+}
+
+// matchRecord returns the fields of a scan match for JSON and CSV. What was sent is the
+// sender's claim from the announcement metadata; transfer is empty if there is none.
+func matchRecord(m scanner.Match) record {
+	var kind, token, amount string
+	if t := m.Transfer; t != nil {
+		switch t.Selector {
+		case stealthaddr.ETHSelector:
+			kind = "eth"
+		case stealthaddr.ERC20TransferSelector:
+			kind = "erc20"
+		case stealthaddr.ERC721SafeTransferFromSelector:
+			kind = "erc721"
+		default:
+			kind = hexutil.Encode(t.Selector[:])
+		}
+		token, amount = t.Token.Hex(), t.Amount.String()
+	}
+	var ephemeral hexutil.Bytes
+	if m.EphemeralPubKey.X != nil {
+		ephemeral = m.EphemeralPubKey.Bytes()
+	}
+	return record{
+		{"block", "block", m.Log.BlockNumber},
+		{"txHash", "tx", m.Log.TxHash.Hex()},
+		{"stealthAddress", "stealth address", m.StealthAddress.Hex()},
+		{"ephemeralPublicKey", "ephemeral public key", ephemeral},
+		{"transfer", "transfer", kind},
+		{"token", "token", token},
+		{"amount", "amount", amount},
+		{"memo", "memo", string(m.Memo)},
+	}
+}
+
+// vectorRecord returns the result of one test vector for JSON and CSV.
+func vectorRecord(name string, mismatches []vectors.Mismatch) record {
+	var details []string
+	for _, m := range mismatches {
+		details = append(details, fmt.Sprintf("%s: got %s, want %s", m.Field, m.Got, m.Want))
+	}
+	return record{
+		{"name", "name", name},
+		{"ok", "ok", len(mismatches) == 0},
+		{"mismatches", "mismatches", strings.Join(details, "; ")},
+	}
+}
+
+// describeTransfer returns a suffix for scan's output describing t, if it is known.
+func describeTransfer(t *stealthaddr.Transfer) string {
+	switch {
+	case t == nil:
+		return ""
+	case t.Selector == stealthaddr.ETHSelector:
+		return fmt.Sprintf(": %s wei", t.Amount)
+	case t.Selector == stealthaddr.ERC20TransferSelector:
+		return fmt.Sprintf(": %s of ERC-20 %s", t.Amount, t.Token)
+	case t.Selector == stealthaddr.ERC721SafeTransferFromSelector:
+		return fmt.Sprintf(": ERC-721 %s #%s", t.Token, t.Amount)
+	}
+	return fmt.Sprintf(": %s of %s via %x", t.Amount, t.Token, t.Selector)
+}
+
+// ephemeralKey returns the ephemeral key with private key s, or a fresh one if s is empty.
+func ephemeralKey(s string) (*stealthaddr.EphemeralKey, error) {
+	if s == "" {
+		return stealthaddr.GenerateEphemeralKey()
+	}
+	r, err := parseKey(s)
+	if err != nil {
+		return nil, err
+	}
+	return stealthaddr.NewEphemeralKey(r)
+}
+
+// ensure0x adds the 0x prefix hexutil expects if s lacks it.
+func ensure0x(s string) string {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s
+	}
+	return "0x" + s
+}
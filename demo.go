@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"stealth/stealthaddr"
+	"stealth/vectors"
+)
+
+// walkthrough is set when the demo prints its human-readable walkthrough: with the default
+// -format text and without -q.
+var walkthrough bool
+
+// printf prints a line of the walkthrough.
+func printf(format string, a ...interface{}) {
+	if walkthrough {
+		fmt.Printf(format, a...)
+	}
+}
+
+// runDemo walks through the scheme step by step with fixed keys, checking every step. With
+// -format json it prints the intermediate values as the reference vector instead, and with
+// -format csv as one row.
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	results := outputFlags(fs)
+	jsonAlias := fs.Bool("json", false, "same as -format json")
+	if err := results.parse(fs, args); err != nil {
+		return err
+	}
+	if *jsonAlias {
+		results.format = "json"
+	}
+	walkthrough = results.text() && !results.quiet
+
+	ref, err := demo()
+	if err != nil {
+		return err
+	}
+	switch results.format {
+	case "json":
+		out, err := ref.Encode()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "csv":
+		return results.write(referenceRecord(ref))
+	}
+	return nil
+}
+
+// referenceRecord returns the values of the demo as a record, with the names of their
+// JSON encoding.
+func referenceRecord(ref *vectors.Reference) record {
+	return record{
+		{"version", "version", ref.Version},
+		{"m", "m", ref.SpendingPrivateKey},
+		{"M", "M", ref.SpendingPublicKey},
+		{"v", "v", ref.ViewingPrivateKey},
+		{"V", "V", ref.ViewingPublicKey},
+		{"metaAddress", "meta-address", ref.MetaAddress},
+		{"r", "r", ref.EphemeralPrivateKey},
+		{"R", "R", ref.EphemeralPublicKey},
+		{"S", "S", ref.SharedSecret},
+		{"hashS", "hash(S)", ref.HashS},
+		{"P", "P", ref.StealthPublicKey},
+		{"address", "A", ref.StealthAddress},
+		{"viewTag", "viewTag", ref.ViewTag},
+		{"p", "p", ref.StealthPrivateKey},
+	}
+}
+
+// demo runs the walkthrough and returns its values, which must reproduce
+// vectors.ReferenceVector.
+func demo() (*vectors.Reference, error) {
+	// 1.
+	// Bob generates a spending key m and a viewing key v, and computes M = G * m and V = G * v,
+	// where G is a commonly-agreed generator point for the elliptic curve.
+	// The stealth meta-address is an encoding of (M, V).
+
+	m, _ := new(big.Int).SetString("3b3b08bba24858f7ab8b302428379198e521359b19784a40aeb4daddf4ad911c", 16)
+	v, _ := new(big.Int).SetString("167ace8d61fc020ae2ba64b8c9fc26a5bf2ac3c2df5f45c04cb1cf506e72bf72", 16)
+	bob := &stealthaddr.Account{SpendingKey: m, ViewingKey: v}
+	meta := bob.MetaAddress()
+	M := meta.SpendingPubKey.Bytes()
+	V := meta.ViewingPubKey.Bytes()
+
+	printf("m: %x\n", m.Bytes())
+	printf("M: %x\n", M)
+	printf("v: %x\n", v.Bytes())
+	printf("V: %x\n", V)
+	printf("meta-address: %s\n", meta)
+
+	// Alice receives the meta-address as a string and decodes it back into (M, V).
+	meta, err := stealthaddr.ParseMetaAddress(meta.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// 2.
+	// Alice generates an ephemeral key r, and publishes the ephemeral public key R = G * r.
+
+	r, _ := new(big.Int).SetString("9d23679323734fdf371017048b4a73cf160566a0ccd69fa087299888d9fbc59f", 16)
+	ephemeral, err := stealthaddr.NewEphemeralKey(r)
+	if err != nil {
+		return nil, err
+	}
+	R := ephemeral.PublicKey.Bytes()
+
+	printf("r: %x\n", r.Bytes())
+	printf("R: %x\n", R)
+
+	// 3.
+	// Alice can compute a shared secret S = V * r, and Bob can compute the same shared secret S = v * R.
+	// Bob only needs the viewing key for this, so the spending key can stay offline.
+
+	S := stealthaddr.SharedSecret(r, meta.ViewingPubKey).Bytes()   // S = V * r
+	S2 := stealthaddr.SharedSecret(v, ephemeral.PublicKey).Bytes() // S = v * R
+
+	printf("S : %x\n", S)
+	printf("S2: %x\n", S2)
+	if string(S) != string(S2) {
+		return nil, errors.New("shared secret does not match")
+	}
+
+	// 4.
+	// In general, in both Bitcoin and Ethereum (including correctly-designed ERC-4337 accounts),
+	// an address is a hash containing the public key used to verify transactions from that address.
+	// So you can compute the address if you compute the public key. To compute the public key,
+	// Alice or Bob can compute P = M + G * hash(S)
+
+	hashS, err := stealthaddr.SharedSecretToScalar(stealthaddr.SharedSecret(r, meta.ViewingPubKey)) //  hash(S) = keccak256(S) % N
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := stealthaddr.ComputeStealthAddress(meta, ephemeral) //  M + G * hash(S)
+	if err != nil {
+		return nil, err
+	}
+	P := payment.PublicKey.Bytes()
+	printf("P: %x\n", P)
+
+	stealthAddress := payment.Address
+	printf("A: %s\n", stealthAddress.String())
+
+	// Alice publishes R and a view tag in an ERC-5564 announcement. Bob checks the view tag
+	// first and only does the full derivation when it matches.
+	announcement, err := stealthaddr.BuildAnnouncement(payment, nil)
+	if err != nil {
+		return nil, err
+	}
+	viewTag := announcement.Metadata[:1]
+	printf("viewTag: %x\n", viewTag)
+	if viewTag[0] != stealthaddr.ViewTag(stealthaddr.SharedSecret(v, ephemeral.PublicKey)) {
+		return nil, errors.New("view tag does not match")
+	}
+
+	// With v and M alone, Bob (or a scanning service he delegates to) finds the same address
+	// from the R he decodes out of the announcement.
+	announcedR, err := announcement.EphemeralPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	found, err := stealthaddr.DeriveStealthAddress(v, meta.SpendingPubKey, announcedR)
+	if err != nil {
+		return nil, err
+	}
+	if found.Address != stealthAddress {
+		return nil, errors.New("scanned address does not match")
+	}
+
+	// 5.
+	// To compute the private key for that address, Bob (and Bob alone) can compute p = m + hash(S)
+	p, err := stealthaddr.DeriveStealthPrivateKey(m, v, ephemeral.PublicKey) // p = (m + hash(S)) % N
+	if err != nil {
+		return nil, err
+	}
+
+	printf("p: %x\n", p.Bytes())
+
+	// 6.
+	// private key to public key
+	P2 := stealthaddr.PublicKey(p).Bytes()
+
+	printf("P2: %x\n", P2)
+	if string(P) != string(P2) {
+		return nil, errors.New("public key does not match")
+	}
+
+	// 7.
+	// A signature made with p must recover to the stealth address, which proves Bob can spend from it.
+	privateKey, err := crypto.ToECDSA(math.PaddedBigBytes(p, 32))
+	if err != nil {
+		return nil, err
+	}
+	digest := crypto.Keccak256([]byte("stealth address demo"))
+	sig, err := crypto.Sign(digest, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return nil, err
+	}
+	signerAddress := crypto.PubkeyToAddress(*signer)
+
+	printf("signer: %s\n", signerAddress.String())
+	if signerAddress != stealthAddress {
+		return nil, errors.New("signer does not match stealth address")
+	}
+
+	// The values of the whole run, which are the reference vector of package vectors.
+	return &vectors.Reference{
+		Version:             vectors.ReferenceVersion,
+		SpendingPrivateKey:  m.Bytes(),
+		SpendingPublicKey:   M,
+		ViewingPrivateKey:   v.Bytes(),
+		ViewingPublicKey:    V,
+		MetaAddress:         meta.String(),
+		EphemeralPrivateKey: r.Bytes(),
+		EphemeralPublicKey:  R,
+		SharedSecret:        S,
+		HashS:               hashS.Bytes(),
+		StealthPublicKey:    P,
+		StealthAddress:      stealthAddress,
+		ViewTag:             viewTag,
+		StealthPrivateKey:   p.Bytes(),
+	}, nil
+}
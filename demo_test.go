@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"stealth/vectors"
+	"testing"
+)
+
+// TestDemoReproducesReferenceVector checks that the walkthrough still derives exactly the
+// published known-answer vector, byte for byte.
+func TestDemoReproducesReferenceVector(t *testing.T) {
+	ref, err := demo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ref.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := vectors.ReferenceVector(); !bytes.Equal(out, want) {
+		t.Fatalf("demo output differs from the reference vector:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"math/big"
+	"stealth/scanner"
+	"stealth/sender"
+	"stealth/signer"
+	"stealth/spend"
+	"stealth/stealthaddr"
+	"testing"
+)
+
+// announcerCode is the creation code of a minimal ERC5564Announcer. Its runtime code
+// emits Announcement(schemeId, stealthAddress, msg.sender, ephemeralPubKey, metadata)
+// for any call, re-encoding the two bytes arguments of the calldata as the event data.
+// It is hand-assembled so the test does not need solc:
+//
+//	CALLDATASIZE PUSH1 0x44 SWAP1 SUB DUP1 PUSH1 0x44 PUSH1 0 CALLDATACOPY  ; mem = calldata[0x44:]
+//	PUSH1 0x40 PUSH1 0x00 MLOAD SUB PUSH1 0x00 MSTORE                     ; rebase the offsets of
+//	PUSH1 0x40 PUSH1 0x20 MLOAD SUB PUSH1 0x20 MSTORE                     ; both bytes arguments
+//	CALLER PUSH1 0x24 CALLDATALOAD PUSH1 0x04 CALLDATALOAD PUSH32 <event id>
+//	DUP5 PUSH1 0 LOG4 STOP
+var announcerCode = hexutil.MustDecode("0x" +
+	"604a80600b6000396000f3" + // CODECOPY the 0x4a bytes of runtime code below and RETURN them
+	"3660449003806044600037" +
+	"604060005103600052" +
+	"604060205103602052" +
+	"33602435600435" + "7f" + common.Bytes2Hex(stealthaddr.AnnouncementEventID.Bytes()) +
+	"846000a400")
+
+// simulatedChain is a simulated backend with the ChainID method the sender and spender need.
+type simulatedChain struct {
+	*backends.SimulatedBackend
+}
+
+func (simulatedChain) ChainID(ctx context.Context) (*big.Int, error) {
+	return params.AllEthashProtocolChanges.ChainID, nil
+}
+
+// fee returns what the mined transaction tx cost its sender in gas.
+func (c simulatedChain) fee(t *testing.T, tx *types.Transaction) *big.Int {
+	t.Helper()
+	ctx := context.Background()
+	receipt, err := c.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("tx %s failed", tx.Hash())
+	}
+	header, err := c.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, err := tx.EffectiveGasTip(header.BaseFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price := tip
+	if header.BaseFee != nil {
+		price = new(big.Int).Add(tip, header.BaseFee)
+	}
+	return new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed))
+}
+
+func (c simulatedChain) balance(t *testing.T, addr common.Address) *big.Int {
+	t.Helper()
+	b, err := c.BalanceAt(context.Background(), addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// TestEndToEnd pays a recipient through a deployed announcer, finds the payment with the
+// viewing key, derives the stealth key and sweeps the funds, checking the balances of the
+// payer, the stealth address and the destination after every step.
+func TestEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	payerKey := newKey(t)
+	payerAddr := crypto.PubkeyToAddress(payerKey.PublicKey)
+	initial := new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	chain := simulatedChain{backends.NewSimulatedBackend(core.GenesisAlloc{payerAddr: {Balance: initial}}, 10_000_000)}
+	defer chain.Close()
+
+	// Deploy the announcer.
+	nonce, err := chain.PendingNonceAt(ctx, payerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploy, err := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 200_000, big.NewInt(params.GWei*10), announcerCode),
+		types.LatestSignerForChainID(params.AllEthashProtocolChanges.ChainID), payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.SendTransaction(ctx, deploy); err != nil {
+		t.Fatal(err)
+	}
+	chain.Commit()
+	announcer := crypto.CreateAddress(payerAddr, nonce)
+	if code, err := chain.CodeAt(ctx, announcer, nil); err != nil || len(code) == 0 {
+		t.Fatalf("announcer not deployed: code %x, err %v", code, err)
+	}
+	spent := chain.fee(t, deploy)
+
+	// The payer sends to the recipient's meta-address.
+	recipient, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := big.NewInt(params.Ether)
+	payment, err := sender.New(chain, payerKey, announcer).Send(ctx, recipient.MetaAddress(), amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Commit()
+	stealthAddr := payment.Stealth.Address
+	if got := chain.balance(t, stealthAddr); got.Cmp(amount) != 0 {
+		t.Fatalf("stealth address holds %v after the payment, want %v", got, amount)
+	}
+	spent.Add(spent, amount)
+	spent.Add(spent, chain.fee(t, payment.Transfer))
+	spent.Add(spent, chain.fee(t, payment.Announce))
+	if got, want := chain.balance(t, payerAddr), new(big.Int).Sub(initial, spent); got.Cmp(want) != 0 {
+		t.Fatalf("payer holds %v after the payment, want %v", got, want)
+	}
+
+	// The recipient finds it with the viewing key alone.
+	s := scanner.NewWatchOnly(chain, announcer, recipient.WatchOnly())
+	matches, err := s.Backfill(ctx, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("scan found %d matches, want 1", len(matches))
+	}
+	m := matches[0]
+	if m.StealthAddress != stealthAddr || m.Log.TxHash != payment.Announce.Hash() {
+		t.Fatalf("scan matched %s in tx %s, want %s in tx %s", m.StealthAddress, m.Log.TxHash, stealthAddr, payment.Announce.Hash())
+	}
+	if m.Transfer == nil || m.Transfer.Amount.Cmp(amount) != 0 {
+		t.Fatalf("scan reports transfer %+v, want %v wei", m.Transfer, amount)
+	}
+	// Someone else's keys find nothing.
+	other, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if none, err := scanner.NewWatchOnly(chain, announcer, other.WatchOnly()).Backfill(ctx, big.NewInt(0), nil); err != nil || len(none) != 0 {
+		t.Fatalf("scan with unrelated keys: %d matches, err %v", len(none), err)
+	}
+
+	// It derives the stealth key and sweeps the funds.
+	stealthSigner, err := signer.NewStealthSigner(recipient, m.EphemeralPubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stealthSigner.Close()
+	if stealthSigner.Address() != stealthAddr {
+		t.Fatalf("stealth signer is for %s, want %s", stealthSigner.Address(), stealthAddr)
+	}
+	dest := crypto.PubkeyToAddress(newKey(t).PublicKey)
+	sweep, err := spend.NewWithSigner(chain, stealthSigner).SweepAll(ctx, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Commit()
+	if got := chain.balance(t, dest); got.Cmp(sweep.Value()) != 0 || got.Sign() <= 0 {
+		t.Fatalf("destination holds %v after the sweep, want %v", got, sweep.Value())
+	}
+	left := new(big.Int).Sub(amount, sweep.Value())
+	left.Sub(left, chain.fee(t, sweep))
+	if got := chain.balance(t, stealthAddr); got.Cmp(left) != 0 {
+		t.Fatalf("stealth address holds %v after the sweep, want %v", got, left)
+	}
+}
+
+// TestPaidTo checks that a payment reports where its funds go: the stealth address, or
+// with PayTo the account it returned, even for a token transfer whose transaction goes to
+// the token contract.
+func TestPaidTo(t *testing.T) {
+	ctx := context.Background()
+	payerKey := newKey(t)
+	payerAddr := crypto.PubkeyToAddress(payerKey.PublicKey)
+	initial := new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	chain := simulatedChain{backends.NewSimulatedBackend(core.GenesisAlloc{payerAddr: {Balance: initial}}, 10_000_000)}
+	defer chain.Close()
+	recipient, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sender.New(chain, payerKey, stealthaddr.AnnouncerAddress)
+
+	payment, err := s.Prepare(ctx, recipient.MetaAddress(), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.PaidTo != payment.Stealth.Address {
+		t.Errorf("ETH payment paid to %s, want the stealth address %s", payment.PaidTo, payment.Stealth.Address)
+	}
+
+	account := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	s.PayTo = func(ctx context.Context, stealthAddress common.Address) (common.Address, error) {
+		return account, nil
+	}
+	token := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	payment, err = s.PrepareTransfer(ctx, recipient.MetaAddress(), stealthaddr.ERC20Transfer(token, big.NewInt(5)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.PaidTo != account {
+		t.Errorf("token payment paid to %s, want the account %s", payment.PaidTo, account)
+	}
+	if to := payment.Transfer.To(); to == nil || *to != token {
+		t.Errorf("token transfer sent to %v, want the token %s", to, token)
+	}
+}
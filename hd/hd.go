@@ -0,0 +1,134 @@
+// Package hd derives stealth accounts deterministically from a BIP-39 mnemonic, so that a
+// single backed-up seed phrase recovers every stealth identity and, by scanning with the
+// recovered viewing keys, every payment received.
+//
+// The keys are derived with BIP-32 along dedicated, fully hardened paths:
+//
+//	spending key of account i: m/5564'/60'/i'/0'
+//	viewing key of account i:  m/5564'/60'/i'/1'
+//
+// 5564 is the ERC number and 60 the Ethereum coin type. Since every step is hardened,
+// neither key nor a leaked extended viewing key reveals anything about the other.
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+	"math/big"
+	"stealth/stealthaddr"
+)
+
+// The components of the derivation paths of stealth accounts.
+const (
+	Purpose  = 5564
+	CoinType = 60
+
+	spendingChild = 0
+	viewingChild  = 1
+)
+
+// HardenedOffset is added to an index to make its derivation hardened.
+const HardenedOffset = 1 << 31
+
+// NewMnemonic returns a new 24-word BIP-39 mnemonic from crypto/rand entropy.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// AccountFromMnemonic returns stealth account index of the BIP-39 mnemonic, with no
+// BIP-39 passphrase. It rejects mnemonics with an invalid word or checksum.
+func AccountFromMnemonic(mnemonic string, index uint32) (*stealthaddr.Account, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	return AccountFromSeed(seed, index)
+}
+
+// AccountFromSeed returns stealth account index of a BIP-39 seed, for example one made
+// with a passphrase by bip39.NewSeed.
+func AccountFromSeed(seed []byte, index uint32) (*stealthaddr.Account, error) {
+	if index >= HardenedOffset {
+		return nil, fmt.Errorf("account index %d out of range", index)
+	}
+	account, err := masterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range []uint32{Purpose, CoinType, index} {
+		if account, err = account.child(i + HardenedOffset); err != nil {
+			return nil, err
+		}
+	}
+	spend, err := account.child(spendingChild + HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+	view, err := account.child(viewingChild + HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+	return &stealthaddr.Account{SpendingKey: spend.key, ViewingKey: view.key}, nil
+}
+
+// extendedKey is a BIP-32 extended private key.
+type extendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+// errInvalidChild is returned for the keys BIP-32 declares invalid, which occur with
+// probability below 2^-127.
+var errInvalidChild = errors.New("derived key is invalid, use the next index")
+
+// masterKey returns the master key of seed.
+func masterKey(seed []byte) (*extendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("seed must be 16 to 64 bytes, got %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+	key := new(big.Int).SetBytes(I[:32])
+	if stealthaddr.ValidateKey(key) != nil {
+		return nil, errInvalidChild
+	}
+	return &extendedKey{key: key, chainCode: I[32:]}, nil
+}
+
+// child returns the child key i, hardened if i >= HardenedOffset.
+func (k *extendedKey) child(i uint32) (*extendedKey, error) {
+	var data []byte
+	if i >= HardenedOffset {
+		data = append([]byte{0}, math.PaddedBigBytes(k.key, 32)...)
+	} else {
+		data = stealthaddr.PublicKey(k.key).Bytes()
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	I := mac.Sum(nil)
+
+	N := crypto.S256().Params().N
+	IL := new(big.Int).SetBytes(I[:32])
+	if IL.Cmp(N) >= 0 {
+		return nil, errInvalidChild
+	}
+	key := IL.Add(IL, k.key)
+	key.Mod(key, N)
+	if key.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &extendedKey{key: key, chainCode: I[32:]}, nil
+}
@@ -0,0 +1,110 @@
+package hd
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/tyler-smith/go-bip39"
+	"stealth/stealthaddr"
+	"strings"
+	"testing"
+)
+
+// TestBIP32Vector1 checks the private keys and chain codes along the chain
+// m/0H/1/2H/2/1000000000 of BIP-32 test vector 1.
+func TestBIP32Vector1(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key, err := masterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		child     uint32
+		path      string
+		key       string
+		chainCode string
+	}{
+		{0, "m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{0 + HardenedOffset, "m/0H", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{1, "m/0H/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{2 + HardenedOffset, "m/0H/1/2H", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f"},
+		{2, "m/0H/1/2H/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd"},
+		{1000000000, "m/0H/1/2H/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e"},
+	}
+	for i, tt := range tests {
+		if i > 0 {
+			if key, err = key.child(tt.child); err != nil {
+				t.Fatalf("%s: %v", tt.path, err)
+			}
+		}
+		if got := hex.EncodeToString(math.PaddedBigBytes(key.key, 32)); got != tt.key {
+			t.Errorf("%s: key %s, want %s", tt.path, got, tt.key)
+		}
+		if got := hex.EncodeToString(key.chainCode); got != tt.chainCode {
+			t.Errorf("%s: chain code %s, want %s", tt.path, got, tt.chainCode)
+		}
+	}
+}
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// TestAccountRoundTrip recovers accounts from the mnemonic alone and checks that they are the
+// accounts made from its seed, that every index is a different account, and that a payment
+// to an account is spendable with the recovered keys.
+func TestAccountRoundTrip(t *testing.T) {
+	seen := make(map[string]uint32)
+	for index := uint32(0); index < 4; index++ {
+		account, err := AccountFromMnemonic(testMnemonic, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromSeed, err := AccountFromSeed(bip39.NewSeed(testMnemonic, ""), index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.SpendingKey.Cmp(fromSeed.SpendingKey) != 0 || account.ViewingKey.Cmp(fromSeed.ViewingKey) != 0 {
+			t.Fatalf("account %d differs between mnemonic and seed", index)
+		}
+		if account.SpendingKey.Cmp(account.ViewingKey) == 0 {
+			t.Fatalf("account %d has equal spending and viewing keys", index)
+		}
+		meta := account.MetaAddress().String()
+		if other, ok := seen[meta]; ok {
+			t.Fatalf("accounts %d and %d have the same meta-address", other, index)
+		}
+		seen[meta] = index
+
+		r, err := stealthaddr.GenerateEphemeralKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		payment, err := stealthaddr.ComputeStealthAddress(account.MetaAddress(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recovered, err := AccountFromMnemonic(testMnemonic, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := stealthaddr.DeriveStealthPrivateKey(recovered.SpendingKey, recovered.ViewingKey, payment.EphemeralPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := stealthaddr.PublicKey(p).Address(); got != payment.Address {
+			t.Fatalf("account %d: recovered key is for %s, want %s", index, got, payment.Address)
+		}
+	}
+}
+
+func TestInvalidMnemonic(t *testing.T) {
+	words := strings.Fields(testMnemonic)
+	badChecksum := strings.Join(append(words[:11:11], "abandon"), " ")
+	unknownWord := strings.Join(append(words[:11:11], "notaword"), " ")
+	for _, mnemonic := range []string{"", badChecksum, unknownWord, strings.Join(words[:11], " ")} {
+		if _, err := AccountFromMnemonic(mnemonic, 0); err == nil {
+			t.Errorf("mnemonic %q accepted", mnemonic)
+		}
+	}
+	if _, err := AccountFromMnemonic(testMnemonic, HardenedOffset); err == nil {
+		t.Error("hardened account index accepted")
+	}
+}
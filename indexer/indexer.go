@@ -0,0 +1,310 @@
+// Package indexer keeps a local copy of the Announcement logs of an ERC5564Announcer
+// contract, so scanning does not have to query the whole chain on every run.
+//
+// Sync fetches the logs of the blocks since the last sync and stores them with the last
+// synced block, so an interrupted sync resumes where it stopped. The Indexer implements
+// ethereum.LogFilterer over the stored logs, so a scanner.Scanner can be pointed at it
+// instead of at a node.
+//
+// Logs are keyed by block number and log index. They are not indexed by view tag: the
+// view tag of an announcement depends on the recipient's viewing key, so a recipient can
+// only learn it by doing the scalar multiplication for every announcement anyway.
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"math/big"
+	"stealth/stealthaddr"
+)
+
+// Backend is the part of an Ethereum client the indexer syncs from. *ethclient.Client implements it.
+type Backend interface {
+	ethereum.LogFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Database keys: the sync cursor, the announcer and start block the database was first
+// synced with, and one entry per log under logPrefix followed by the 8-byte block number
+// and the 4-byte log index, so iteration is in chain order.
+var (
+	syncedKey = []byte("synced")
+	originKey = []byte("origin")
+	logPrefix = []byte("l")
+)
+
+// DefaultBatchSize is the number of blocks Sync requests logs for at a time.
+const DefaultBatchSize = 10_000
+
+// DefaultConfirmations is how far behind the head Sync stays, so that the blocks it
+// stores are unlikely to be reorganized away.
+const DefaultConfirmations = 12
+
+// Indexer stores the announcements of one announcer contract in a key-value database.
+type Indexer struct {
+	backend   Backend
+	db        ethdb.KeyValueStore
+	announcer common.Address
+	start     uint64
+
+	// BatchSize is the number of blocks requested from the backend at a time.
+	BatchSize uint64
+	// Confirmations is the number of most recent blocks Sync leaves out.
+	Confirmations uint64
+}
+
+// New returns an indexer of the announcer contract that stores its logs in db and starts
+// syncing at block start, usually the block the contract was deployed in. A database keeps
+// the logs of one announcer from one start block: Sync and FilterLogs fail on a database
+// first synced with a different announcer or start block, as its cursor says nothing about
+// the blocks or the contract this indexer would leave out.
+func New(backend Backend, db ethdb.KeyValueStore, announcer common.Address, start uint64) *Indexer {
+	return &Indexer{
+		backend:       backend,
+		db:            db,
+		announcer:     announcer,
+		start:         start,
+		BatchSize:     DefaultBatchSize,
+		Confirmations: DefaultConfirmations,
+	}
+}
+
+// OpenLevelDB opens, or creates, the LevelDB database in the directory path.
+func OpenLevelDB(path string) (ethdb.KeyValueStore, error) {
+	return leveldb.New(path, 16, 16, "stealth/indexer/", false)
+}
+
+// Synced returns the last block whose logs are stored, and false if nothing was synced yet.
+func (idx *Indexer) Synced() (uint64, bool, error) {
+	if ok, err := idx.db.Has(syncedKey); err != nil || !ok {
+		return 0, false, err
+	}
+	b, err := idx.db.Get(syncedKey)
+	if err != nil {
+		return 0, false, err
+	}
+	return binary.BigEndian.Uint64(b), true, nil
+}
+
+// Sync stores the announcements from the block after the last synced one up to the
+// head minus Confirmations, one batch of blocks at a time. Each batch is written together
+// with the new cursor, so if Sync fails or ctx is cancelled, the next call carries on
+// after the last complete batch. It returns the last synced block.
+func (idx *Indexer) Sync(ctx context.Context) (uint64, error) {
+	if idx.BatchSize == 0 {
+		return 0, errors.New("indexer BatchSize must be at least 1")
+	}
+	if err := idx.checkOrigin(); err != nil {
+		return 0, err
+	}
+	head, err := idx.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	synced, ok, err := idx.Synced()
+	if err != nil {
+		return 0, err
+	}
+	from := idx.start
+	if ok {
+		from = synced + 1
+	}
+	if head.Number.Uint64() < idx.Confirmations {
+		return synced, nil
+	}
+	target := head.Number.Uint64() - idx.Confirmations
+	for from <= target {
+		to := from + idx.BatchSize - 1
+		if to > target {
+			to = target
+		}
+		if err := idx.syncRange(ctx, from, to); err != nil {
+			return synced, err
+		}
+		synced, from = to, to+1
+	}
+	return synced, nil
+}
+
+// origin encodes the announcer and start block of the indexer as stored under originKey.
+func (idx *Indexer) origin() []byte {
+	origin := make([]byte, common.AddressLength+8)
+	copy(origin, idx.announcer.Bytes())
+	binary.BigEndian.PutUint64(origin[common.AddressLength:], idx.start)
+	return origin
+}
+
+// checkOrigin fails if the database was synced with a different announcer or start block.
+func (idx *Indexer) checkOrigin() error {
+	ok, err := idx.db.Has(originKey)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Nothing synced yet, so syncRange will record this indexer's origin. A cursor
+		// without an origin is from before origins were recorded and cannot be checked.
+		_, synced, err := idx.Synced()
+		if err == nil && synced {
+			err = errors.New("index has no record of its announcer and start block, sync a new one")
+		}
+		return err
+	}
+	stored, err := idx.db.Get(originKey)
+	if err != nil {
+		return err
+	}
+	if len(stored) != common.AddressLength+8 {
+		return errors.New("invalid index origin")
+	}
+	if !bytes.Equal(stored, idx.origin()) {
+		return fmt.Errorf("index was synced for announcer %s from block %d, not %s from block %d",
+			common.BytesToAddress(stored[:common.AddressLength]), binary.BigEndian.Uint64(stored[common.AddressLength:]),
+			idx.announcer, idx.start)
+	}
+	return nil
+}
+
+// syncRange stores the announcements of the blocks [from, to] and moves the cursor to to.
+func (idx *Indexer) syncRange(ctx context.Context, from, to uint64) error {
+	logs, err := idx.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{idx.announcer},
+		Topics:    [][]common.Hash{{stealthaddr.AnnouncementEventID}},
+	})
+	if err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		enc, err := json.Marshal(log)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(logKey(log.BlockNumber, log.Index), enc); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(originKey, idx.origin()); err != nil {
+		return err
+	}
+	var cursor [8]byte
+	binary.BigEndian.PutUint64(cursor[:], to)
+	if err := batch.Put(syncedKey, cursor[:]); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+func logKey(block uint64, index uint) []byte {
+	key := make([]byte, len(logPrefix)+12)
+	copy(key, logPrefix)
+	binary.BigEndian.PutUint64(key[len(logPrefix):], block)
+	binary.BigEndian.PutUint32(key[len(logPrefix)+8:], uint32(index))
+	return key
+}
+
+// Logs returns the stored logs of the blocks [from, to] in chain order.
+func (idx *Indexer) Logs(from, to uint64) ([]types.Log, error) {
+	start := logKey(from, 0)
+	it := idx.db.NewIterator(logPrefix, start[len(logPrefix):])
+	defer it.Release()
+
+	var logs []types.Log
+	for it.Next() {
+		var log types.Log
+		if err := json.Unmarshal(it.Value(), &log); err != nil {
+			return nil, err
+		}
+		if log.BlockNumber > to {
+			break
+		}
+		logs = append(logs, log)
+	}
+	return logs, it.Error()
+}
+
+// FilterLogs implements ethereum.LogFilterer over the stored logs. Only logs of blocks
+// that were synced are returned; BlockHash queries are not supported.
+func (idx *Indexer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if q.BlockHash != nil {
+		return nil, errors.New("indexer does not support block hash queries")
+	}
+	if err := idx.checkOrigin(); err != nil {
+		return nil, err
+	}
+	synced, ok, err := idx.Synced()
+	if err != nil || !ok {
+		return nil, err
+	}
+	from, to := uint64(0), synced
+	if q.FromBlock != nil {
+		from = q.FromBlock.Uint64()
+	}
+	if q.ToBlock != nil && q.ToBlock.Sign() >= 0 && q.ToBlock.Uint64() < to {
+		to = q.ToBlock.Uint64()
+	}
+	logs, err := idx.Logs(from, to)
+	if err != nil {
+		return nil, err
+	}
+	matched := logs[:0]
+	for _, log := range logs {
+		if matches(q, log) {
+			matched = append(matched, log)
+		}
+	}
+	return matched, nil
+}
+
+// SubscribeFilterLogs is not supported: the indexer only holds what Sync stored.
+func (idx *Indexer) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("indexer does not support subscriptions, subscribe on the node instead")
+}
+
+// matches reports whether log passes the address and topic filters of q, with the same
+// semantics as eth_getLogs.
+func matches(q ethereum.FilterQuery, log types.Log) bool {
+	if len(q.Addresses) > 0 && !containsAddress(q.Addresses, log.Address) {
+		return false
+	}
+	if len(q.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, options := range q.Topics {
+		if len(options) > 0 && !containsHash(options, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAddress(list []common.Address, a common.Address) bool {
+	for _, x := range list {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(list []common.Hash, h common.Hash) bool {
+	for _, x := range list {
+		if x == h {
+			return true
+		}
+	}
+	return false
+}
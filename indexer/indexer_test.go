@@ -0,0 +1,147 @@
+package indexer
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"math/big"
+	"stealth/stealthaddr"
+	"testing"
+)
+
+// chain is a Backend with one announcement log in every block up to head.
+type chain struct {
+	announcer common.Address
+	head      uint64
+}
+
+func (c *chain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: new(big.Int).SetUint64(c.head)}, nil
+}
+
+func (c *chain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for b := q.FromBlock.Uint64(); b <= q.ToBlock.Uint64() && b <= c.head; b++ {
+		logs = append(logs, types.Log{
+			Address:     c.announcer,
+			Topics:      []common.Hash{stealthaddr.AnnouncementEventID},
+			BlockNumber: b,
+		})
+	}
+	return logs, nil
+}
+
+func (c *chain) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, nil
+}
+
+func TestSyncResumes(t *testing.T) {
+	ctx := context.Background()
+	announcer := common.HexToAddress("0x55649E01B5Df198D18D95b5cc5051630cfD45564")
+	backend := &chain{announcer: announcer, head: 50}
+	db := memorydb.New()
+
+	idx := New(backend, db, announcer, 10)
+	idx.BatchSize, idx.Confirmations = 7, 0
+	if synced, err := idx.Sync(ctx); err != nil || synced != 50 {
+		t.Fatalf("Sync = %d, %v, want 50", synced, err)
+	}
+	backend.head = 60
+	idx = New(backend, db, announcer, 10)
+	idx.Confirmations = 0
+	if synced, err := idx.Sync(ctx); err != nil || synced != 60 {
+		t.Fatalf("resumed Sync = %d, %v, want 60", synced, err)
+	}
+	logs, err := idx.FilterLogs(ctx, ethereum.FilterQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 51 {
+		t.Fatalf("%d logs, want 51", len(logs))
+	}
+	if logs[0].BlockNumber != 10 {
+		t.Fatalf("first log in block %d, want 10", logs[0].BlockNumber)
+	}
+}
+
+func TestSyncRejectsOtherOrigin(t *testing.T) {
+	ctx := context.Background()
+	announcer := common.HexToAddress("0x55649E01B5Df198D18D95b5cc5051630cfD45564")
+	backend := &chain{announcer: announcer, head: 100}
+	db := memorydb.New()
+	if _, err := New(backend, db, announcer, 50).Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		announcer common.Address
+		start     uint64
+	}{
+		{"earlier start", announcer, 0},
+		{"later start", announcer, 60},
+		{"other announcer", common.HexToAddress("0x01"), 50},
+	}
+	for _, tt := range tests {
+		idx := New(backend, db, tt.announcer, tt.start)
+		if _, err := idx.Sync(ctx); err == nil {
+			t.Errorf("%s: Sync succeeds", tt.name)
+		}
+		if _, err := idx.FilterLogs(ctx, ethereum.FilterQuery{}); err == nil {
+			t.Errorf("%s: FilterLogs succeeds", tt.name)
+		}
+	}
+}
+
+func TestSyncRejectsZeroBatchSize(t *testing.T) {
+	announcer := common.HexToAddress("0x55649E01B5Df198D18D95b5cc5051630cfD45564")
+	idx := New(&chain{announcer: announcer, head: 50}, memorydb.New(), announcer, 0)
+	idx.BatchSize = 0
+	if _, err := idx.Sync(context.Background()); err == nil {
+		t.Fatal("Sync with BatchSize 0 succeeds")
+	}
+}
+
+// TestQueryRangeIndependentOfStart reopens an index with its own start block and queries it
+// with different ranges, as repeated scans with different -from and -to do.
+func TestQueryRangeIndependentOfStart(t *testing.T) {
+	ctx := context.Background()
+	announcer := common.HexToAddress("0x55649E01B5Df198D18D95b5cc5051630cfD45564")
+	backend := &chain{announcer: announcer, head: 100}
+	db := memorydb.New()
+	idx := New(backend, db, announcer, 20)
+	idx.Confirmations = 0
+	if _, err := idx.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.head = 120
+	idx = New(backend, db, announcer, 20)
+	idx.Confirmations = 0
+	if _, err := idx.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		from, to         int64
+		first, last, all uint64
+	}{
+		{0, -1, 20, 120, 101},
+		{50, 60, 50, 60, 11},
+		{110, -1, 110, 120, 11},
+	}
+	for _, tt := range tests {
+		q := ethereum.FilterQuery{FromBlock: big.NewInt(tt.from)}
+		if tt.to >= 0 {
+			q.ToBlock = big.NewInt(tt.to)
+		}
+		logs, err := idx.FilterLogs(ctx, q)
+		if err != nil {
+			t.Fatalf("[%d, %d]: %v", tt.from, tt.to, err)
+		}
+		if uint64(len(logs)) != tt.all || logs[0].BlockNumber != tt.first || logs[len(logs)-1].BlockNumber != tt.last {
+			t.Fatalf("[%d, %d]: %d logs, want %d from block %d to %d", tt.from, tt.to, len(logs), tt.all, tt.first, tt.last)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package txutil fills in the gas and fee fields of the transactions the sender and
+// spend packages build.
+package txutil
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+)
+
+// Fees are the fee parameters of a transaction. GasPrice is set on chains without a base
+// fee; GasTipCap and GasFeeCap are set on EIP-1559 chains.
+type Fees struct {
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// SuggestFees returns fees based on the backend's suggestions and the latest base fee.
+func SuggestFees(ctx context.Context, backend bind.ContractTransactor) (*Fees, error) {
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		gasPrice, err := backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Fees{GasPrice: gasPrice}, nil
+	}
+	tip, err := backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Leave room for the base fee to double before the transaction is included.
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	return &Fees{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// MaxCost returns the most that gas units of gas can cost at these fees.
+func (f *Fees) MaxCost(gas uint64) *big.Int {
+	price := f.GasFeeCap
+	if f.GasPrice != nil {
+		price = f.GasPrice
+	}
+	return new(big.Int).Mul(price, new(big.Int).SetUint64(gas))
+}
+
+// Tx returns a transaction with these fees: a legacy transaction if GasPrice is set and
+// an EIP-1559 transaction otherwise.
+func (f *Fees) Tx(nonce uint64, gas uint64, to common.Address, value *big.Int, data []byte) types.TxData {
+	if f.GasPrice != nil {
+		return &types.LegacyTx{Nonce: nonce, GasPrice: f.GasPrice, Gas: gas, To: &to, Value: value, Data: data}
+	}
+	return &types.DynamicFeeTx{Nonce: nonce, GasTipCap: f.GasTipCap, GasFeeCap: f.GasFeeCap, Gas: gas, To: &to, Value: value, Data: data}
+}
+
+// NewTx returns a transaction from from to to with the given nonce, with its gas limit
+// estimated and its fees set by SuggestFees.
+func NewTx(ctx context.Context, backend bind.ContractTransactor, from common.Address, nonce uint64, to common.Address, value *big.Int, data []byte) (types.TxData, error) {
+	gas, err := backend.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &to, Value: value, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	fees, err := SuggestFees(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+	return fees.Tx(nonce, gas, to, value, data), nil
+}
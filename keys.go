@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"os"
+	"stealth/keystore"
+	"stealth/stealthaddr"
+	"strings"
+)
+
+// readPassphrase returns the first line of the file at path.
+func readPassphrase(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("need -password-file to encrypt or decrypt a keystore")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r"), nil
+}
+
+func writeKeyFile(path, passwordFile string, account *stealthaddr.Account) error {
+	passphrase, err := readPassphrase(passwordFile)
+	if err != nil {
+		return err
+	}
+	return keystore.Save(path, account, passphrase)
+}
+
+func readKeyFile(path, passwordFile string) (*stealthaddr.Account, error) {
+	f, err := keystore.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	return f.Unlock(passphrase)
+}
+
+// watchOnlyFile is the file export-watchonly writes: the viewing key in the clear and the
+// spending public key, enough to scan but not to spend.
+type watchOnlyFile struct {
+	ViewingKey     hexutil.Bytes `json:"viewingKey"`
+	SpendingPubKey hexutil.Bytes `json:"spendingPubKey"`
+	MetaAddress    string        `json:"metaAddress"`
+}
+
+func writeWatchOnlyFile(path string, w *stealthaddr.WatchOnlyAccount) error {
+	out, err := json.MarshalIndent(watchOnlyFile{
+		ViewingKey:     math.PaddedBigBytes(w.ViewingKey, 32),
+		SpendingPubKey: w.SpendingPubKey.Bytes(),
+		MetaAddress:    w.MetaAddress().String(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0600)
+}
+
+func readWatchOnlyFile(path string) (*stealthaddr.WatchOnlyAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f watchOnlyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	view, err := crypto.ToECDSA(f.ViewingKey)
+	if err != nil {
+		return nil, err
+	}
+	M, err := stealthaddr.ParsePoint(f.SpendingPubKey)
+	if err != nil {
+		return nil, err
+	}
+	return stealthaddr.NewWatchOnlyAccount(view.D, M)
+}
+
+// parseECDSAKey parses a 32-byte hex private key, with or without a 0x prefix.
+func parseECDSAKey(s string) (*ecdsa.PrivateKey, error) {
+	return crypto.HexToECDSA(strings.TrimPrefix(s, "0x"))
+}
+
+// parseKey is parseECDSAKey returning the bare scalar.
+func parseKey(s string) (*big.Int, error) {
+	key, err := parseECDSAKey(s)
+	if err != nil {
+		return nil, err
+	}
+	return key.D, nil
+}
+
+// keyFlags are the flags through which a command gets the recipient's keys: either a
+// keystore or the keys themselves. For watch-only use the viewing key can be combined with
+// the meta-address instead of the spending key, or both read from a watch-only file.
+type keyFlags struct {
+	keyFile       string
+	passwordFile  string
+	watchOnlyFile string
+	spendingKey   string
+	viewingKey    string
+	meta          string
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet, watchOnly bool) {
+	fs.StringVar(&k.keyFile, "keyfile", "", "keystore written by keygen -out")
+	fs.StringVar(&k.passwordFile, "password-file", "", "file holding the passphrase of -keyfile")
+	fs.StringVar(&k.viewingKey, "viewing-key", "", "viewing private key (hex)")
+	if watchOnly {
+		fs.StringVar(&k.watchOnlyFile, "watchonly", "", "watch-only file written by export-watchonly")
+		fs.StringVar(&k.meta, "meta", "", "meta-address (st:eth:0x...), to scan with the viewing key only")
+	} else {
+		fs.StringVar(&k.spendingKey, "spending-key", "", "spending private key (hex)")
+	}
+}
+
+// account returns both private keys.
+func (k *keyFlags) account() (*stealthaddr.Account, error) {
+	if k.keyFile != "" {
+		return readKeyFile(k.keyFile, k.passwordFile)
+	}
+	if k.spendingKey == "" || k.viewingKey == "" {
+		return nil, errors.New("need -keyfile or both -spending-key and -viewing-key")
+	}
+	spend, err := parseKey(k.spendingKey)
+	if err != nil {
+		return nil, err
+	}
+	view, err := parseKey(k.viewingKey)
+	if err != nil {
+		return nil, err
+	}
+	return &stealthaddr.Account{SpendingKey: spend, ViewingKey: view}, nil
+}
+
+// watchOnly returns the watch-only part of the keys.
+func (k *keyFlags) watchOnly() (*stealthaddr.WatchOnlyAccount, error) {
+	if k.watchOnlyFile != "" {
+		return readWatchOnlyFile(k.watchOnlyFile)
+	}
+	if k.keyFile != "" {
+		account, err := readKeyFile(k.keyFile, k.passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		return account.WatchOnly(), nil
+	}
+	if k.viewingKey == "" || k.meta == "" {
+		return nil, errors.New("need -watchonly, -keyfile or both -viewing-key and -meta")
+	}
+	view, err := parseKey(k.viewingKey)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := stealthaddr.ParseMetaAddress(k.meta)
+	if err != nil {
+		return nil, err
+	}
+	return stealthaddr.NewWatchOnlyAccount(view, meta.SpendingPubKey)
+}
@@ -0,0 +1,116 @@
+// Package keystore persists a stealth account in an encrypted JSON file. Both private keys
+// are encrypted with the scrypt and AES-128-CTR scheme of go-ethereum keystore files, so
+// each "crypto" section can be read by any tool that understands that format.
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	gethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"stealth/stealthaddr"
+)
+
+// Version is the version of the file format written by Encrypt.
+const Version = 1
+
+// The scrypt parameters Save uses, the same as those of go-ethereum's keystore.
+const (
+	StandardScryptN = gethkeystore.StandardScryptN
+	StandardScryptP = gethkeystore.StandardScryptP
+)
+
+// ErrDecrypt is returned by Unlock when the passphrase is wrong.
+var ErrDecrypt = gethkeystore.ErrDecrypt
+
+// File is a decoded keystore file. The meta-address is stored in the clear, so it can be
+// shown without the passphrase; the keys need Unlock.
+type File struct {
+	Version     int                     `json:"version"`
+	MetaAddress string                  `json:"metaAddress"`
+	SpendingKey gethkeystore.CryptoJSON `json:"spendingKey"`
+	ViewingKey  gethkeystore.CryptoJSON `json:"viewingKey"`
+}
+
+// Encrypt encrypts the keys of account with passphrase, using the scrypt parameters
+// scryptN and scryptP, and returns the JSON keystore file.
+func Encrypt(account *stealthaddr.Account, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	spend, err := gethkeystore.EncryptDataV3(math.PaddedBigBytes(account.SpendingKey, 32), []byte(passphrase), scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	view, err := gethkeystore.EncryptDataV3(math.PaddedBigBytes(account.ViewingKey, 32), []byte(passphrase), scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(File{
+		Version:     Version,
+		MetaAddress: account.MetaAddress().String(),
+		SpendingKey: spend,
+		ViewingKey:  view,
+	}, "", "  ")
+}
+
+// Save encrypts account with passphrase and writes it to path, readable only by its owner.
+func Save(path string, account *stealthaddr.Account, passphrase string) error {
+	data, err := Encrypt(account, passphrase, StandardScryptN, StandardScryptP)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Decode parses a keystore file without decrypting it.
+func Decode(data []byte) (*File, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported keystore version %d", f.Version)
+	}
+	return &f, nil
+}
+
+// Load reads the keystore file at path without decrypting it.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Meta returns the meta-address stored in the file.
+func (f *File) Meta() (*stealthaddr.MetaAddress, error) {
+	return stealthaddr.ParseMetaAddress(f.MetaAddress)
+}
+
+// Unlock decrypts the keys with passphrase. It returns ErrDecrypt if the passphrase is
+// wrong, and an error if the keys do not match the stored meta-address.
+func (f *File) Unlock(passphrase string) (*stealthaddr.Account, error) {
+	spend, err := gethkeystore.DecryptDataV3(f.SpendingKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	view, err := gethkeystore.DecryptDataV3(f.ViewingKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	spendKey, err := crypto.ToECDSA(spend)
+	if err != nil {
+		return nil, err
+	}
+	viewKey, err := crypto.ToECDSA(view)
+	if err != nil {
+		return nil, err
+	}
+	account := &stealthaddr.Account{SpendingKey: spendKey.D, ViewingKey: viewKey.D}
+	if account.MetaAddress().String() != f.MetaAddress {
+		return nil, errors.New("keys do not match the meta-address of the keystore")
+	}
+	return account, nil
+}
@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"errors"
+	gethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"stealth/stealthaddr"
+	"testing"
+)
+
+// encryptTest encrypts a fresh account with the light scrypt parameters, to keep the tests fast.
+func encryptTest(t *testing.T, passphrase string) (*stealthaddr.Account, *File) {
+	t.Helper()
+	account, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Encrypt(account, passphrase, gethkeystore.LightScryptN, gethkeystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account, f
+}
+
+func TestRoundTrip(t *testing.T) {
+	account, f := encryptTest(t, "correct horse")
+	meta, err := f.Meta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.String() != account.MetaAddress().String() {
+		t.Fatalf("stored meta-address %s, want %s", meta, account.MetaAddress())
+	}
+	unlocked, err := f.Unlock("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlocked.SpendingKey.Cmp(account.SpendingKey) != 0 || unlocked.ViewingKey.Cmp(account.ViewingKey) != 0 {
+		t.Fatal("unlocked keys differ from the encrypted ones")
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	_, f := encryptTest(t, "correct horse")
+	if _, err := f.Unlock("battery staple"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("error %v, want ErrDecrypt", err)
+	}
+}
+
+// flipHex returns the hex string s with the lowest bit of its first byte flipped.
+func flipHex(t *testing.T, s string) string {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 1
+	return hex.EncodeToString(b)
+}
+
+func TestCorrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(f *File)
+	}{
+		{"spending key ciphertext", func(f *File) { f.SpendingKey.CipherText = flipHex(t, f.SpendingKey.CipherText) }},
+		{"viewing key ciphertext", func(f *File) { f.ViewingKey.CipherText = flipHex(t, f.ViewingKey.CipherText) }},
+		{"spending key MAC", func(f *File) { f.SpendingKey.MAC = flipHex(t, f.SpendingKey.MAC) }},
+		{"viewing key MAC", func(f *File) { f.ViewingKey.MAC = flipHex(t, f.ViewingKey.MAC) }},
+	}
+	for _, tt := range tests {
+		_, f := encryptTest(t, "correct horse")
+		tt.corrupt(f)
+		if _, err := f.Unlock("correct horse"); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: error %v, want ErrDecrypt", tt.name, err)
+		}
+	}
+}
+
+func TestMetaAddressMismatch(t *testing.T) {
+	_, f := encryptTest(t, "correct horse")
+	other, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.MetaAddress = other.MetaAddress().String()
+	_, err = f.Unlock("correct horse")
+	if err == nil || errors.Is(err, ErrDecrypt) {
+		t.Fatalf("error %v, want a meta-address mismatch", err)
+	}
+}
+
+func TestDecodeRejectsVersion(t *testing.T) {
+	if _, err := Decode([]byte(`{"version": 2}`)); err == nil {
+		t.Fatal("version 2 decodes")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// field is one value of a command's result. name is its stable key in JSON and its column
+// in CSV; label is how the text output introduces it. Bytes are 0x-prefixed hex and
+// addresses checksummed hex in every format.
+type field struct {
+	name  string
+	label string
+	value interface{}
+}
+
+// record is a command's result: its fields in output order.
+type record []field
+
+// output writes the results of a command in the format chosen with -format.
+type output struct {
+	format string
+	quiet  bool
+}
+
+// outputFlags registers -format and -q on fs.
+func outputFlags(fs *flag.FlagSet) *output {
+	o := &output{}
+	fs.StringVar(&o.format, "format", "text", "output format: text, json or csv")
+	fs.BoolVar(&o.quiet, "q", false, "quiet: print only the results, no progress messages")
+	return o
+}
+
+// parse parses args into fs, which has the output flags registered, and rejects an
+// unknown -format.
+func (o *output) parse(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	switch o.format {
+	case "text", "json", "csv":
+		return nil
+	}
+	return fmt.Errorf("unknown -format %q, use text, json or csv", o.format)
+}
+
+// text reports whether the output is for people rather than scripts.
+func (o *output) text() bool {
+	return o.format == "text"
+}
+
+// info prints a progress message. In JSON and CSV mode it goes to stderr, so that stdout
+// holds only the result; -q drops it altogether.
+func (o *output) info(format string, a ...interface{}) {
+	if o.quiet {
+		return
+	}
+	if o.text() {
+		fmt.Printf(format, a...)
+	} else {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
+
+// write prints a single result: one "label: value" line per field as text, an object in
+// JSON, and a header row and a value row in CSV.
+func (o *output) write(r record) error {
+	switch o.format {
+	case "json":
+		b, err := jsonObject(r)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	case "csv":
+		return writeCSV(r, []record{r})
+	}
+	for _, f := range r {
+		fmt.Printf("%s: %v\n", f.label, f.value)
+	}
+	return nil
+}
+
+// writeList prints a list of results with the fields of header, as a JSON array or as CSV
+// rows under one header row. Commands print lists as text themselves, one line per item.
+func (o *output) writeList(header record, rows []record) error {
+	if o.format == "csv" {
+		return writeCSV(header, rows)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, r := range rows {
+		b, err := jsonObject(r)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		buf.Write(bytes.ReplaceAll(b, []byte("\n"), []byte("\n  ")))
+	}
+	if len(rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+	fmt.Println(buf.String())
+	return nil
+}
+
+// jsonObject encodes r as an indented JSON object with the fields in order.
+func jsonObject(r record) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, f := range r {
+		key, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, "\n  %s: %s", key, value)
+	}
+	buf.WriteString("\n}")
+	return buf.Bytes(), nil
+}
+
+// writeCSV writes the names of header as the header row and the values of rows below it.
+func writeCSV(header record, rows []record) error {
+	w := csv.NewWriter(os.Stdout)
+	names := make([]string, len(header))
+	for i, f := range header {
+		names[i] = f.name
+	}
+	w.Write(names)
+	for _, r := range rows {
+		values := make([]string, len(r))
+		for i, f := range r {
+			values[i] = fmt.Sprint(f.value)
+		}
+		w.Write(values)
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,130 @@
+// Package registry reads and writes stealth meta-addresses on the ERC-6538 registry
+// contract, so a sender can look up a recipient's meta-address from their regular
+// address instead of getting it out of band.
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"stealth/internal/txutil"
+	"stealth/stealthaddr"
+	"strings"
+)
+
+// Address is the address of the canonical ERC-6538 registry singleton, deployed at the
+// same address on every chain that has it.
+var Address = common.HexToAddress("0x6538E6bf4B0eBd30A8Ea093027Ac2422ce5d6538")
+
+// ABI is the part of the ERC-6538 registry ABI this package uses.
+const ABI = `[
+	{"type":"event","name":"StealthMetaAddressSet","anonymous":false,"inputs":[
+		{"name":"registrant","type":"address","indexed":true},
+		{"name":"schemeId","type":"uint256","indexed":true},
+		{"name":"stealthMetaAddress","type":"bytes","indexed":false}
+	]},
+	{"type":"function","name":"registerKeys","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"schemeId","type":"uint256"},
+		{"name":"stealthMetaAddress","type":"bytes"}
+	]},
+	{"type":"function","name":"stealthMetaAddressOf","stateMutability":"view","inputs":[
+		{"name":"registrant","type":"address"},
+		{"name":"schemeId","type":"uint256"}
+	],"outputs":[
+		{"name":"","type":"bytes"}
+	]}
+]`
+
+var registryABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(ABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// ErrNotRegistered is returned by LookupMetaAddress when the registrant has not
+// registered a meta-address for the scheme.
+var ErrNotRegistered = errors.New("no meta-address registered")
+
+// Backend is the part of an Ethereum client the registry uses. *ethclient.Client implements it.
+type Backend interface {
+	bind.ContractCaller
+	bind.ContractTransactor
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Registry is a client of the ERC-6538 registry contract.
+type Registry struct {
+	backend Backend
+	address common.Address
+}
+
+// New returns a client of the registry contract at address (usually Address).
+func New(backend Backend, address common.Address) *Registry {
+	return &Registry{backend: backend, address: address}
+}
+
+// RegisterMetaAddress signs and sends a registerKeys() transaction from the account of key
+// that sets its meta-address for schemeID to meta.
+func (r *Registry) RegisterMetaAddress(ctx context.Context, key *ecdsa.PrivateKey, schemeID *big.Int, meta *stealthaddr.MetaAddress) (*types.Transaction, error) {
+	calldata, err := registryABI.Pack("registerKeys", schemeID, meta.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	chainID, err := r.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := r.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := txutil.NewTx(ctx, r.backend, from, nonce, r.address, new(big.Int), calldata)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.backend.SendTransaction(ctx, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// LookupMetaAddress returns the meta-address registrant registered for schemeID, as of
+// the latest block. It returns ErrNotRegistered if there is none.
+func (r *Registry) LookupMetaAddress(ctx context.Context, registrant common.Address, schemeID *big.Int) (*stealthaddr.MetaAddress, error) {
+	calldata, err := registryABI.Pack("stealthMetaAddressOf", registrant, schemeID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.backend.CallContract(ctx, ethereum.CallMsg{To: &r.address, Data: calldata}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := registryABI.Unpack("stealthMetaAddressOf", out)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stealthMetaAddressOf result: %w", err)
+	}
+	b := values[0].([]byte)
+	if len(b) == 0 {
+		return nil, ErrNotRegistered
+	}
+	meta, err := stealthaddr.MetaAddressFromBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("registered meta-address of %s is invalid: %w", registrant, err)
+	}
+	return meta, nil
+}
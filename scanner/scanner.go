@@ -0,0 +1,182 @@
+// Package scanner finds the stealth payments addressed to a recipient among the
+// Announcement events of an ERC5564Announcer contract.
+//
+// It only needs the recipient's viewing key and spending public key, never the spending key.
+package scanner
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"math/big"
+	"stealth/stealthaddr"
+)
+
+// Backend is the part of an Ethereum client the scanner uses. *ethclient.Client implements it.
+type Backend interface {
+	ethereum.LogFilterer
+}
+
+// Match is an announcement that turned out to be a payment to the recipient.
+type Match struct {
+	Announcement *stealthaddr.Announcement
+	// StealthAddress is the address the recipient derived; it equals Announcement.StealthAddress.
+	StealthAddress common.Address
+	// EphemeralPubKey is the decoded R, needed to derive the stealth private key.
+	EphemeralPubKey stealthaddr.Point
+	// Transfer is what the payment sent according to the announcement metadata, or nil if
+	// the sender only published the view tag. It is the sender's claim, not checked on chain.
+	Transfer *stealthaddr.Transfer
+	// Memo is the decrypted memo the sender attached, or nil if there is none or it does
+	// not decrypt.
+	Memo []byte
+	// Log is the event log the announcement was decoded from.
+	Log types.Log
+}
+
+// newMatch returns the match of the announcement a, logged in log, with payment.
+func newMatch(a *stealthaddr.Announcement, payment *stealthaddr.StealthPayment, log types.Log) Match {
+	// Metadata this package cannot decode still carries a valid view tag, so the payment
+	// is reported without the transfer details.
+	t, _ := a.Transfer()
+	memo, _ := payment.DecryptMemo(a)
+	return Match{
+		Announcement:    a,
+		StealthAddress:  payment.Address,
+		EphemeralPubKey: payment.EphemeralPublicKey,
+		Transfer:        t,
+		Memo:            memo,
+		Log:             log,
+	}
+}
+
+// Scanner checks announcements against one recipient's keys.
+type Scanner struct {
+	backend        Backend
+	announcer      common.Address
+	viewingKey     *big.Int
+	spendingPubKey stealthaddr.Point
+}
+
+// New returns a scanner for the recipient with viewing key v and spending public key M,
+// reading announcements from the ERC5564Announcer contract at announcer
+// (usually stealthaddr.AnnouncerAddress).
+func New(backend Backend, announcer common.Address, viewingKey *big.Int, spendingPubKey stealthaddr.Point) *Scanner {
+	return &Scanner{
+		backend:        backend,
+		announcer:      announcer,
+		viewingKey:     viewingKey,
+		spendingPubKey: spendingPubKey,
+	}
+}
+
+// NewWatchOnly returns a scanner for the watch-only account w, as New does for its keys.
+// This is the delegated mode: a scanning service given only w finds the recipient's
+// payments, and the matches it returns are all it learns; it cannot derive their keys.
+func NewWatchOnly(backend Backend, announcer common.Address, w *stealthaddr.WatchOnlyAccount) *Scanner {
+	return New(backend, announcer, w.ViewingKey, w.SpendingPubKey)
+}
+
+// query returns the filter for secp256k1 announcements in the block range [from, to].
+// A nil bound leaves that side of the range open.
+func (s *Scanner) query(from, to *big.Int) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{s.announcer},
+		Topics: [][]common.Hash{
+			{stealthaddr.AnnouncementEventID},
+			{common.BigToHash(big.NewInt(stealthaddr.SchemeIDSecp256k1))},
+		},
+	}
+}
+
+// check returns the match for log, or nil if the log is not a payment to the recipient.
+// Announcements are untrusted input, so logs that fail to decode are skipped rather than
+// treated as errors; otherwise anyone could stop the scan by announcing garbage.
+func (s *Scanner) check(log types.Log) *Match {
+	if log.Removed {
+		return nil
+	}
+	a, err := stealthaddr.ParseAnnouncement(log)
+	if err != nil {
+		return nil
+	}
+	payment, ok, err := stealthaddr.CheckAnnouncement(s.viewingKey, s.spendingPubKey, a)
+	if err != nil || !ok {
+		return nil
+	}
+	m := newMatch(a, payment, log)
+	return &m
+}
+
+// Backfill scans the announcements emitted in the block range [from, to]. It fails if the
+// recipient's keys are invalid.
+func (s *Scanner) Backfill(ctx context.Context, from, to *big.Int) ([]Match, error) {
+	if _, err := stealthaddr.NewWatchOnlyAccount(s.viewingKey, s.spendingPubKey); err != nil {
+		return nil, err
+	}
+	logs, err := s.backend.FilterLogs(ctx, s.query(from, to))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		announcements []*stealthaddr.Announcement
+		announced     []types.Log
+	)
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		if a, err := stealthaddr.ParseAnnouncement(log); err == nil {
+			announcements = append(announcements, a)
+			announced = append(announced, log)
+		}
+	}
+	found, err := stealthaddr.ScanBatch(s.viewingKey, s.spendingPubKey, announcements)
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for _, m := range found {
+		matches = append(matches, newMatch(announcements[m.Index], m.Payment, announced[m.Index]))
+	}
+	return matches, nil
+}
+
+// Subscribe delivers matches for new announcements to the matches channel until the
+// subscription is unsubscribed or the backend subscription fails. It fails at once if the
+// recipient's keys are invalid.
+func (s *Scanner) Subscribe(ctx context.Context, matches chan<- Match) (ethereum.Subscription, error) {
+	if _, err := stealthaddr.NewWatchOnlyAccount(s.viewingKey, s.spendingPubKey); err != nil {
+		return nil, err
+	}
+	logs := make(chan types.Log)
+	sub, err := s.backend.SubscribeFilterLogs(ctx, s.query(nil, nil), logs)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				m := s.check(log)
+				if m == nil {
+					continue
+				}
+				select {
+				case matches <- *m:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
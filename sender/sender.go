@@ -0,0 +1,229 @@
+// Package sender pays a recipient at a fresh stealth address: it derives the address from the
+// recipient's meta-address, transfers ETH or tokens to it, and announces the payment on the
+// ERC5564Announcer contract so the recipient can find it.
+package sender
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"stealth/internal/txutil"
+	"stealth/signer"
+	"stealth/stealthaddr"
+	"strings"
+)
+
+// Backend is the part of an Ethereum client the sender uses. *ethclient.Client implements it.
+type Backend interface {
+	bind.ContractTransactor
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Payment is a stealth payment with its two signed transactions: the transfer to the
+// stealth address and the announce() call.
+type Payment struct {
+	Stealth      *stealthaddr.StealthPayment
+	Announcement *stealthaddr.Announcement
+	// PaidTo is where the funds go: the stealth address, or the address PayTo returned for it.
+	PaidTo   common.Address
+	Transfer *types.Transaction
+	Announce *types.Transaction
+}
+
+// Sender sends stealth payments from one account.
+type Sender struct {
+	backend   Backend
+	signer    signer.Signer
+	from      common.Address
+	announcer common.Address
+
+	// PayTo, if set, returns the address the funds for a stealth address are sent to, for
+	// example the ERC-4337 account it owns from userop.AccountAddress, so the recipient
+	// can sweep them without holding ETH. The announcement still names the stealth address,
+	// so the recipient's scanner finds the payment as usual.
+	PayTo func(ctx context.Context, stealthAddress common.Address) (common.Address, error)
+}
+
+// New returns a sender that pays from the account of key and announces on the
+// ERC5564Announcer contract at announcer (usually stealthaddr.AnnouncerAddress).
+func New(backend Backend, key *ecdsa.PrivateKey, announcer common.Address) *Sender {
+	return NewWithSigner(backend, signer.NewKeySigner(key), announcer)
+}
+
+// NewWithSigner is New for an account whose key is held by s, such as a hardware wallet
+// or clef behind a signer.WalletSigner.
+func NewWithSigner(backend Backend, s signer.Signer, announcer common.Address) *Sender {
+	return &Sender{
+		backend:   backend,
+		signer:    s,
+		from:      s.Address(),
+		announcer: announcer,
+	}
+}
+
+// tokenABI holds the token functions a payment can call.
+const tokenABI = `[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","outputs":[{"name":"","type":"bool"}],"inputs":[
+		{"name":"to","type":"address"},
+		{"name":"amount","type":"uint256"}
+	]},
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"from","type":"address"},
+		{"name":"to","type":"address"},
+		{"name":"tokenId","type":"uint256"}
+	]}
+]`
+
+var tokens = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(tokenABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// transferCall returns the recipient, value and calldata of the transaction that makes
+// the transfer t from from to the stealth address to.
+func transferCall(from, to common.Address, t *stealthaddr.Transfer) (common.Address, *big.Int, []byte, error) {
+	if t.Amount == nil || t.Amount.Sign() < 0 || t.Amount.BitLen() > 256 {
+		return common.Address{}, nil, nil, errors.New("transfer amount must be a uint256")
+	}
+	switch t.Selector {
+	case stealthaddr.ETHSelector:
+		return to, t.Amount, nil, nil
+	case stealthaddr.ERC20TransferSelector:
+		data, err := tokens.Pack("transfer", to, t.Amount)
+		return t.Token, new(big.Int), data, err
+	case stealthaddr.ERC721SafeTransferFromSelector:
+		data, err := tokens.Pack("safeTransferFrom", from, to, t.Amount)
+		return t.Token, new(big.Int), data, err
+	}
+	return common.Address{}, nil, nil, fmt.Errorf("unsupported transfer selector %x", t.Selector)
+}
+
+// Prepare derives a stealth address for meta with a fresh ephemeral key and signs the
+// transfer of amount wei to it and the matching announcement, without sending anything.
+func (s *Sender) Prepare(ctx context.Context, meta *stealthaddr.MetaAddress, amount *big.Int) (*Payment, error) {
+	return s.PrepareTransfer(ctx, meta, stealthaddr.ETHTransfer(amount), nil)
+}
+
+// PrepareTransfer is Prepare for any transfer: ETH, ERC-20 (stealthaddr.ERC20Transfer) or
+// ERC-721 (stealthaddr.ERC721Transfer). The announcement metadata records the token and
+// amount, so the recipient's scanner can tell what arrived. A non-empty memo is encrypted
+// to the recipient and appended to the metadata.
+func (s *Sender) PrepareTransfer(ctx context.Context, meta *stealthaddr.MetaAddress, t *stealthaddr.Transfer, memo []byte) (*Payment, error) {
+	payer, err := stealthaddr.NewPayer(meta)
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareWithPayer(ctx, payer, t, memo)
+}
+
+// PrepareWithPayer is PrepareTransfer with the stealth address taken from payer, so that
+// repeated payments to one meta-address reuse its decoded keys and precomputed payments.
+func (s *Sender) PrepareWithPayer(ctx context.Context, payer *stealthaddr.Payer, t *stealthaddr.Transfer, memo []byte) (*Payment, error) {
+	stealth, err := payer.Next()
+	if err != nil {
+		return nil, err
+	}
+	recipient := stealth.Address
+	if s.PayTo != nil {
+		if recipient, err = s.PayTo(ctx, stealth.Address); err != nil {
+			return nil, err
+		}
+	}
+	to, value, data, err := transferCall(s.from, recipient, t)
+	if err != nil {
+		return nil, err
+	}
+	var announcement *stealthaddr.Announcement
+	if len(memo) > 0 {
+		announcement, err = stealthaddr.BuildAnnouncementWithMemo(stealth, t, memo)
+	} else {
+		announcement, err = stealthaddr.BuildAnnouncement(stealth, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	calldata, err := stealthaddr.EncodeAnnounceCalldata(announcement)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID, err := s.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := s.backend.PendingNonceAt(ctx, s.from)
+	if err != nil {
+		return nil, err
+	}
+	transfer, err := txutil.NewTx(ctx, s.backend, s.from, nonce, to, value, data)
+	if err != nil {
+		return nil, err
+	}
+	signedTransfer, err := s.signer.SignTx(types.NewTx(transfer), chainID)
+	if err != nil {
+		return nil, err
+	}
+	announce, err := txutil.NewTx(ctx, s.backend, s.from, nonce+1, s.announcer, new(big.Int), calldata)
+	if err != nil {
+		return nil, err
+	}
+	signedAnnounce, err := s.signer.SignTx(types.NewTx(announce), chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payment{
+		Stealth:      stealth,
+		Announcement: announcement,
+		PaidTo:       recipient,
+		Transfer:     signedTransfer,
+		Announce:     signedAnnounce,
+	}, nil
+}
+
+// Send prepares a payment of amount wei to meta and broadcasts both transactions,
+// the transfer first.
+func (s *Sender) Send(ctx context.Context, meta *stealthaddr.MetaAddress, amount *big.Int) (*Payment, error) {
+	return s.SendTransfer(ctx, meta, stealthaddr.ETHTransfer(amount), nil)
+}
+
+// SendTransfer is Send for any transfer, see PrepareTransfer.
+func (s *Sender) SendTransfer(ctx context.Context, meta *stealthaddr.MetaAddress, t *stealthaddr.Transfer, memo []byte) (*Payment, error) {
+	payment, err := s.PrepareTransfer(ctx, meta, t, memo)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.broadcast(ctx, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// SendWithPayer is SendTransfer with the stealth address taken from payer, see PrepareWithPayer.
+func (s *Sender) SendWithPayer(ctx context.Context, payer *stealthaddr.Payer, t *stealthaddr.Transfer, memo []byte) (*Payment, error) {
+	payment, err := s.PrepareWithPayer(ctx, payer, t, memo)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.broadcast(ctx, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// broadcast sends the transfer and then the announcement of payment.
+func (s *Sender) broadcast(ctx context.Context, payment *Payment) error {
+	if err := s.backend.SendTransaction(ctx, payment.Transfer); err != nil {
+		return err
+	}
+	return s.backend.SendTransaction(ctx, payment.Announce)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"log"
+	"math/big"
+	"net/http"
+	"stealth/keystore"
+	"stealth/stealthaddr"
+	"time"
+)
+
+// deriveRequest is the body of POST /derive.
+type deriveRequest struct {
+	MetaAddress string `json:"metaAddress"`
+}
+
+// deriveResponse is a stealth payment derived for the sender, with what it has to announce.
+type deriveResponse struct {
+	StealthAddress   common.Address `json:"stealthAddress"`
+	EphemeralPubKey  hexutil.Bytes  `json:"ephemeralPubKey"`
+	ViewTag          hexutil.Bytes  `json:"viewTag"`
+	AnnounceCalldata hexutil.Bytes  `json:"announceCalldata"`
+}
+
+// announcementJSON is an announcement as the API exchanges it.
+type announcementJSON struct {
+	SchemeID        uint64         `json:"schemeId"`
+	StealthAddress  common.Address `json:"stealthAddress"`
+	EphemeralPubKey hexutil.Bytes  `json:"ephemeralPubKey"`
+	Metadata        hexutil.Bytes  `json:"metadata"`
+}
+
+// scanRequest is the body of POST /scan. The recipient is identified by the viewing key and
+// either the spending public key or the meta-address.
+type scanRequest struct {
+	ViewingKey     hexutil.Bytes      `json:"viewingKey"`
+	SpendingPubKey hexutil.Bytes      `json:"spendingPubKey,omitempty"`
+	MetaAddress    string             `json:"metaAddress,omitempty"`
+	Announcements  []announcementJSON `json:"announcements"`
+}
+
+// scanMatch is an announcement of a scan request that pays the recipient.
+type scanMatch struct {
+	Index          int            `json:"index"`
+	StealthAddress common.Address `json:"stealthAddress"`
+	Memo           hexutil.Bytes  `json:"memo,omitempty"`
+}
+
+type scanResponse struct {
+	Matches []scanMatch `json:"matches"`
+}
+
+type metaAddressResponse struct {
+	MetaAddress string `json:"metaAddress"`
+}
+
+// maxScanAnnouncements is the most announcements a scan request may carry. Scanning costs a
+// scalar multiplication per announcement, about a second for this many on one core.
+const maxScanAnnouncements = 10_000
+
+// Timeouts of the HTTP server, so that slow or idle clients cannot hold connections open.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = time.Minute
+	idleTimeout       = 2 * time.Minute
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// server serves the HTTP API of the serve command.
+type server struct {
+	// meta is the meta-address GET /metaaddress returns, nil if none was configured.
+	meta *stealthaddr.MetaAddress
+}
+
+// httpServer returns the HTTP server of the API on addr, with the timeouts set.
+func (s *server) httpServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/derive", s.derive)
+	mux.HandleFunc("/scan", s.scan)
+	mux.HandleFunc("/metaaddress", s.metaAddress)
+	return mux
+}
+
+// decodePost decodes the JSON body of a POST request into req. It writes the error response
+// and returns false if the request is not a POST or the body does not decode.
+func decodePost(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"use POST"})
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<20)).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("invalid request body: %v", err)})
+		return false
+	}
+	return true
+}
+
+// respond writes resp, or err as a bad request.
+func respond(w http.ResponseWriter, resp interface{}, err error) {
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) derive(w http.ResponseWriter, r *http.Request) {
+	var req deriveRequest
+	if decodePost(w, r, &req) {
+		resp, err := s.deriveResponse(&req)
+		respond(w, resp, err)
+	}
+}
+
+func (s *server) deriveResponse(req *deriveRequest) (*deriveResponse, error) {
+	meta, err := stealthaddr.ParseMetaAddress(req.MetaAddress)
+	if err != nil {
+		return nil, err
+	}
+	r, err := stealthaddr.GenerateEphemeralKey()
+	if err != nil {
+		return nil, err
+	}
+	payment, err := stealthaddr.ComputeStealthAddress(meta, r)
+	if err != nil {
+		return nil, err
+	}
+	announcement, err := stealthaddr.BuildAnnouncement(payment, nil)
+	if err != nil {
+		return nil, err
+	}
+	calldata, err := stealthaddr.EncodeAnnounceCalldata(announcement)
+	if err != nil {
+		return nil, err
+	}
+	return &deriveResponse{
+		StealthAddress:   payment.Address,
+		EphemeralPubKey:  payment.EphemeralPublicKey.Bytes(),
+		ViewTag:          []byte{payment.ViewTag},
+		AnnounceCalldata: calldata,
+	}, nil
+}
+
+func (s *server) scan(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	if decodePost(w, r, &req) {
+		resp, err := s.scanResponse(&req)
+		respond(w, resp, err)
+	}
+}
+
+func (s *server) scanResponse(req *scanRequest) (*scanResponse, error) {
+	if len(req.Announcements) > maxScanAnnouncements {
+		return nil, fmt.Errorf("at most %d announcements per request", maxScanAnnouncements)
+	}
+	if len(req.ViewingKey) != 32 {
+		return nil, errors.New("viewingKey must be 32 bytes")
+	}
+	viewingKey := new(big.Int).SetBytes(req.ViewingKey)
+	var spendingPubKey stealthaddr.Point
+	switch {
+	case len(req.SpendingPubKey) > 0:
+		M, err := stealthaddr.ParsePoint(req.SpendingPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spendingPubKey: %w", err)
+		}
+		spendingPubKey = M
+	case req.MetaAddress != "":
+		meta, err := stealthaddr.ParseMetaAddress(req.MetaAddress)
+		if err != nil {
+			return nil, err
+		}
+		spendingPubKey = meta.SpendingPubKey
+	default:
+		return nil, errors.New("need spendingPubKey or metaAddress")
+	}
+	watchOnly, err := stealthaddr.NewWatchOnlyAccount(viewingKey, spendingPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	announcements := make([]*stealthaddr.Announcement, len(req.Announcements))
+	for i, a := range req.Announcements {
+		announcements[i] = &stealthaddr.Announcement{
+			SchemeID:        new(big.Int).SetUint64(a.SchemeID),
+			StealthAddress:  a.StealthAddress,
+			EphemeralPubKey: a.EphemeralPubKey,
+			Metadata:        a.Metadata,
+		}
+	}
+	found, err := stealthaddr.ScanBatch(watchOnly.ViewingKey, watchOnly.SpendingPubKey, announcements)
+	if err != nil {
+		return nil, err
+	}
+	resp := &scanResponse{Matches: []scanMatch{}}
+	for _, m := range found {
+		match := scanMatch{Index: m.Index, StealthAddress: m.Payment.Address}
+		match.Memo, _ = m.Payment.DecryptMemo(announcements[m.Index])
+		resp.Matches = append(resp.Matches, match)
+	}
+	return resp, nil
+}
+
+func (s *server) metaAddress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"use GET"})
+		return
+	}
+	if s.meta == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{"no meta-address configured, start serve with -keyfile or -meta"})
+		return
+	}
+	writeJSON(w, http.StatusOK, metaAddressResponse{s.meta.String()})
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	keyFile := fs.String("keyfile", "", "keystore whose meta-address GET /metaaddress returns (no passphrase needed)")
+	metaFlag := fs.String("meta", "", "meta-address GET /metaaddress returns, instead of -keyfile")
+	fs.Parse(args)
+
+	var s server
+	switch {
+	case *keyFile != "":
+		f, err := keystore.Load(*keyFile)
+		if err != nil {
+			return err
+		}
+		if s.meta, err = f.Meta(); err != nil {
+			return err
+		}
+	case *metaFlag != "":
+		meta, err := stealthaddr.ParseMetaAddress(*metaFlag)
+		if err != nil {
+			return err
+		}
+		s.meta = meta
+	}
+	log.Printf("listening on http://%s", *addr)
+	return s.httpServer(*addr).ListenAndServe()
+}
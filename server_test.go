@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stealth/stealthaddr"
+	"strings"
+	"testing"
+)
+
+// post sends body, JSON-encoded unless it is a string, to path on the handler of s.
+func post(t *testing.T, s *server, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	raw, ok := body.(string)
+	if !ok {
+		enc, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw = string(enc)
+	}
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(raw)))
+	return w
+}
+
+// decodeResponse decodes the JSON body of w into v after checking its status.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeriveThenScan(t *testing.T) {
+	bob, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s server
+	var derived deriveResponse
+	decodeResponse(t, post(t, &s, "/derive", deriveRequest{MetaAddress: bob.MetaAddress().String()}), http.StatusOK, &derived)
+	if len(derived.AnnounceCalldata) == 0 {
+		t.Fatal("no announce calldata")
+	}
+
+	other, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unrelated deriveResponse
+	decodeResponse(t, post(t, &s, "/derive", deriveRequest{MetaAddress: other.MetaAddress().String()}), http.StatusOK, &unrelated)
+
+	announcement := func(d deriveResponse) announcementJSON {
+		return announcementJSON{
+			SchemeID:        stealthaddr.SchemeIDSecp256k1,
+			StealthAddress:  d.StealthAddress,
+			EphemeralPubKey: d.EphemeralPubKey,
+			Metadata:        d.ViewTag,
+		}
+	}
+	var scanned scanResponse
+	decodeResponse(t, post(t, &s, "/scan", scanRequest{
+		ViewingKey:    bob.ViewingKey.FillBytes(make([]byte, 32)),
+		MetaAddress:   bob.MetaAddress().String(),
+		Announcements: []announcementJSON{announcement(unrelated), announcement(derived)},
+	}), http.StatusOK, &scanned)
+	if len(scanned.Matches) != 1 || scanned.Matches[0].Index != 1 || scanned.Matches[0].StealthAddress != derived.StealthAddress {
+		t.Fatalf("matches %+v, want announcement 1 for %s", scanned.Matches, derived.StealthAddress)
+	}
+}
+
+func TestMetaAddress(t *testing.T) {
+	bob, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(s *server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metaaddress", nil))
+		return w
+	}
+	var resp metaAddressResponse
+	decodeResponse(t, get(&server{meta: bob.MetaAddress()}), http.StatusOK, &resp)
+	if resp.MetaAddress != bob.MetaAddress().String() {
+		t.Fatalf("meta-address %s, want %s", resp.MetaAddress, bob.MetaAddress())
+	}
+	if w := get(&server{}); w.Code != http.StatusNotFound {
+		t.Errorf("without a meta-address: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := post(t, &server{meta: bob.MetaAddress()}, "/metaaddress", "{}"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	tests := []struct {
+		name, path, body string
+	}{
+		{"truncated JSON", "/derive", `{"metaAddress": "st:eth:0x`},
+		{"not JSON", "/scan", "viewingKey=0x01"},
+		{"invalid meta-address", "/derive", `{"metaAddress": "st:eth:0x1234"}`},
+		{"short viewing key", "/scan", `{"viewingKey": "0x01", "metaAddress": "st:eth:0x"}`},
+		{"wrong type", "/scan", `{"announcements": {}}`},
+	}
+	var s server
+	for _, tt := range tests {
+		w := post(t, &s, tt.path, tt.body)
+		var resp errorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); w.Code != http.StatusBadRequest || err != nil || resp.Error == "" {
+			t.Errorf("%s: status %d, error %q, want %d with an error message", tt.name, w.Code, resp.Error, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestScanLimitsAnnouncements(t *testing.T) {
+	bob, err := stealthaddr.GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := func(n int) int {
+		return post(t, &server{}, "/scan", scanRequest{
+			ViewingKey:    bob.ViewingKey.FillBytes(make([]byte, 32)),
+			MetaAddress:   bob.MetaAddress().String(),
+			Announcements: make([]announcementJSON, n),
+		}).Code
+	}
+	if code := scan(maxScanAnnouncements); code != http.StatusOK {
+		t.Errorf("%d announcements: status %d, want %d", maxScanAnnouncements, code, http.StatusOK)
+	}
+	if code := scan(maxScanAnnouncements + 1); code != http.StatusBadRequest {
+		t.Errorf("%d announcements: status %d, want %d", maxScanAnnouncements+1, code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPServerTimeouts(t *testing.T) {
+	var s server
+	srv := s.httpServer("127.0.0.1:0")
+	if srv.ReadHeaderTimeout != readHeaderTimeout || srv.ReadTimeout != readTimeout ||
+		srv.WriteTimeout != writeTimeout || srv.IdleTimeout != idleTimeout {
+		t.Fatalf("timeouts: read header %v, read %v, write %v, idle %v", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+	if srv.ReadHeaderTimeout <= 0 || srv.WriteTimeout <= 0 {
+		t.Fatal("read header and write timeouts must be set")
+	}
+}
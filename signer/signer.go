@@ -0,0 +1,170 @@
+// Package signer abstracts over where the keys that sign transactions live: in memory, in an
+// external signer such as clef, or in a hardware wallet.
+//
+// Stealth addresses limit what external signers can do. The private key of a stealth
+// address is p = m + hash(S), which no hardware wallet or clef can compute from the
+// spending key m it holds, so spending from a stealth address always needs m in memory.
+// StealthSigner is the one place that handles p: it derives it, signs with it and wipes
+// it on Close. Everything else, such as the sender paying a meta-address, can use a
+// WalletSigner and never see a raw key.
+package signer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"stealth/stealthaddr"
+	"sync"
+)
+
+// Signer signs transactions from one account.
+type Signer interface {
+	// Address returns the account the signer signs for.
+	Address() common.Address
+	// SignTx returns tx signed for the chain chainID.
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner returns a signer for a private key held in memory.
+func NewKeySigner(key *ecdsa.PrivateKey) Signer {
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *keySigner) Address() common.Address { return s.address }
+
+func (s *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// WalletSigner signs with an account of a go-ethereum wallet, for example a hardware
+// wallet from accounts/usbwallet or the external signer of NewClefSigner. The key never
+// leaves the wallet.
+type WalletSigner struct {
+	wallet  accounts.Wallet
+	account accounts.Account
+}
+
+// NewWalletSigner returns a signer for account in wallet. The wallet must already be open.
+func NewWalletSigner(wallet accounts.Wallet, account accounts.Account) (*WalletSigner, error) {
+	if !wallet.Contains(account) {
+		return nil, fmt.Errorf("wallet %s has no account %s", wallet.URL(), account.Address)
+	}
+	return &WalletSigner{wallet: wallet, account: account}, nil
+}
+
+// NewClefSigner returns a signer for address through the clef instance at endpoint
+// (an IPC path or HTTP URL). Clef asks its user to approve every transaction.
+func NewClefSigner(endpoint string, address common.Address) (*WalletSigner, error) {
+	wallet, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return NewWalletSigner(wallet, accounts.Account{Address: address})
+}
+
+// Address returns the wallet account.
+func (s *WalletSigner) Address() common.Address { return s.account.Address }
+
+// SignTx asks the wallet to sign tx.
+func (s *WalletSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return s.wallet.SignTx(s.account, tx, chainID)
+}
+
+// StealthSigner signs with the private key of one stealth address. It derives the key from
+// the recipient's account when it is created, keeps the only copy, and zeroes it on Close.
+type StealthSigner struct {
+	mu      sync.Mutex
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewStealthSigner returns a signer for the stealth address announced with the ephemeral
+// public key R, deriving its private key from account. Callers should Close it as soon as
+// the transactions are signed, and may zero account's keys with ZeroAccount once they no
+// longer need them.
+func NewStealthSigner(account *stealthaddr.Account, R stealthaddr.Point) (*StealthSigner, error) {
+	p, err := stealthaddr.DeriveStealthPrivateKey(account.SpendingKey, account.ViewingKey, R)
+	if err != nil {
+		return nil, err
+	}
+	b := math.PaddedBigBytes(p, 32)
+	key, err := crypto.ToECDSA(b)
+	zeroBytes(b)
+	zeroInt(p)
+	if err != nil {
+		return nil, err
+	}
+	return &StealthSigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+// Address returns the stealth address.
+func (s *StealthSigner) Address() common.Address { return s.address }
+
+// SignTx signs tx with the stealth private key. It fails after Close.
+func (s *StealthSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return nil, fmt.Errorf("stealth signer for %s is closed", s.address)
+	}
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// SignHash signs the 32-byte hash with the stealth private key and returns the 65-byte
+// [R || S || V] signature of crypto.Sign, with V 0 or 1. Smart accounts owned by the
+// stealth address, such as the ERC-4337 accounts of package userop, need it to authorize
+// operations. It fails after Close.
+func (s *StealthSigner) SignHash(hash []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return nil, fmt.Errorf("stealth signer for %s is closed", s.address)
+	}
+	return crypto.Sign(hash, s.key)
+}
+
+// Close zeroes the stealth private key. The signer cannot sign afterwards.
+func (s *StealthSigner) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		zeroInt(s.key.D)
+		s.key = nil
+	}
+}
+
+// ZeroAccount overwrites both private keys of account with zeros.
+func ZeroAccount(account *stealthaddr.Account) {
+	zeroInt(account.SpendingKey)
+	zeroInt(account.ViewingKey)
+}
+
+// zeroInt overwrites the words of k. Copies the runtime made earlier, for example when k
+// grew, are out of reach; this only limits how long the key stays in memory.
+func zeroInt(k *big.Int) {
+	if k == nil {
+		return
+	}
+	words := k.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	k.SetInt64(0)
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
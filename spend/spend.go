@@ -0,0 +1,106 @@
+// Package spend moves funds out of a stealth address using the stealth private key the
+// recipient derives for a matched announcement.
+package spend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"stealth/internal/txutil"
+	"stealth/signer"
+)
+
+// ErrInsufficientBalance is returned when the stealth address cannot cover the amount plus fees.
+var ErrInsufficientBalance = errors.New("insufficient balance to cover amount and fees")
+
+// Backend is the part of an Ethereum client the spender uses. *ethclient.Client implements it.
+type Backend interface {
+	bind.ContractTransactor
+	ChainID(ctx context.Context) (*big.Int, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// Spender sends transactions from one stealth address.
+type Spender struct {
+	backend Backend
+	signer  signer.Signer
+	from    common.Address
+}
+
+// New returns a spender for the stealth address of key, typically obtained with
+// stealthaddr.Account.StealthKey for a scanner match.
+func New(backend Backend, key *ecdsa.PrivateKey) *Spender {
+	return NewWithSigner(backend, signer.NewKeySigner(key))
+}
+
+// NewWithSigner returns a spender that signs with s, usually a signer.StealthSigner so the
+// stealth private key is wiped once the spender is done.
+func NewWithSigner(backend Backend, s signer.Signer) *Spender {
+	return &Spender{backend: backend, signer: s, from: s.Address()}
+}
+
+// Address returns the stealth address the spender sends from.
+func (s *Spender) Address() common.Address {
+	return s.from
+}
+
+// send signs tx and broadcasts it.
+func (s *Spender) send(ctx context.Context, tx types.TxData) (*types.Transaction, error) {
+	chainID, err := s.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := s.signer.SignTx(types.NewTx(tx), chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.backend.SendTransaction(ctx, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// Transfer sends amount wei from the stealth address to to.
+func (s *Spender) Transfer(ctx context.Context, to common.Address, amount *big.Int) (*types.Transaction, error) {
+	nonce, err := s.backend.PendingNonceAt(ctx, s.from)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := txutil.NewTx(ctx, s.backend, s.from, nonce, to, amount, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, tx)
+}
+
+// SweepAll sends the whole balance of the stealth address to to, minus the maximum fee
+// the transaction can cost. On EIP-1559 chains the part of the fee cap that is not
+// charged is refunded, so a small remainder can stay behind.
+func (s *Spender) SweepAll(ctx context.Context, to common.Address) (*types.Transaction, error) {
+	balance, err := s.backend.BalanceAt(ctx, s.from, nil)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := s.backend.PendingNonceAt(ctx, s.from)
+	if err != nil {
+		return nil, err
+	}
+	gas, err := s.backend.EstimateGas(ctx, ethereum.CallMsg{From: s.from, To: &to, Value: balance})
+	if err != nil {
+		return nil, err
+	}
+	fees, err := txutil.SuggestFees(ctx, s.backend)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Int).Sub(balance, fees.MaxCost(gas))
+	if value.Sign() <= 0 {
+		return nil, ErrInsufficientBalance
+	}
+	return s.send(ctx, fees.Tx(nonce, gas, to, value, nil))
+}
@@ -0,0 +1,121 @@
+package stealthaddr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+)
+
+// SchemeIDSecp256k1 is the ERC-5564 scheme id of stealth addresses on secp256k1 with view tags.
+const SchemeIDSecp256k1 = 1
+
+// AnnouncerAddress is the address of the canonical ERC5564Announcer singleton,
+// deployed at the same address on every chain that has it.
+var AnnouncerAddress = common.HexToAddress("0x55649E01B5Df198D18D95b5cc5051630cfD45564")
+
+// AnnouncerABI is the ABI of the ERC5564Announcer contract.
+const AnnouncerABI = `[
+	{"type":"event","name":"Announcement","anonymous":false,"inputs":[
+		{"name":"schemeId","type":"uint256","indexed":true},
+		{"name":"stealthAddress","type":"address","indexed":true},
+		{"name":"caller","type":"address","indexed":true},
+		{"name":"ephemeralPubKey","type":"bytes","indexed":false},
+		{"name":"metadata","type":"bytes","indexed":false}
+	]},
+	{"type":"function","name":"announce","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"schemeId","type":"uint256"},
+		{"name":"stealthAddress","type":"address"},
+		{"name":"ephemeralPubKey","type":"bytes"},
+		{"name":"metadata","type":"bytes"}
+	]}
+]`
+
+var announcerABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(AnnouncerABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// AnnouncementEventID is the topic of the ERC5564Announcer Announcement event.
+var AnnouncementEventID = announcerABI.Events["Announcement"].ID
+
+// Announcement is the data a sender publishes for a payment, as emitted by the
+// ERC5564Announcer contract.
+type Announcement struct {
+	SchemeID       *big.Int
+	StealthAddress common.Address
+	// Caller is the account that called announce(). It is only set on parsed announcements.
+	Caller common.Address
+	// EphemeralPubKey is R in compressed SEC1 form.
+	EphemeralPubKey []byte
+	// Metadata starts with the view tag, optionally followed by the transfer details.
+	Metadata []byte
+}
+
+// EphemeralPublicKey decodes the compressed ephemeral public key R of the announcement.
+func (a *Announcement) EphemeralPublicKey() (Point, error) {
+	R, err := ParsePoint(a.EphemeralPubKey)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid ephemeral public key: %w", err)
+	}
+	return R, nil
+}
+
+// ViewTag returns the view tag of the shared secret S: the most significant byte of
+// keccak256(S), as specified by EIP-5564. A recipient can compare it against the first
+// metadata byte of an announcement and skip the rest of the derivation for the vast
+// majority of payments that are not theirs.
+func ViewTag(S Point) byte {
+	return hashSharedSecret(S)[0]
+}
+
+// BuildAnnouncement returns the announcement a sender publishes for payment. If t is not
+// nil, the metadata records what was sent after the view tag, see EncodeMetadata.
+func BuildAnnouncement(payment *StealthPayment, t *Transfer) (*Announcement, error) {
+	metadata, err := EncodeMetadata(payment.ViewTag, t)
+	if err != nil {
+		return nil, err
+	}
+	return &Announcement{
+		SchemeID:        big.NewInt(SchemeIDSecp256k1),
+		StealthAddress:  payment.Address,
+		EphemeralPubKey: payment.EphemeralPublicKey.Bytes(),
+		Metadata:        metadata,
+	}, nil
+}
+
+// EncodeAnnounceCalldata returns the calldata of the ERC5564Announcer announce() call for a.
+func EncodeAnnounceCalldata(a *Announcement) ([]byte, error) {
+	return announcerABI.Pack("announce", a.SchemeID, a.StealthAddress, a.EphemeralPubKey, a.Metadata)
+}
+
+// ParseAnnouncement decodes an Announcement event log emitted by the ERC5564Announcer contract.
+func ParseAnnouncement(log types.Log) (*Announcement, error) {
+	if len(log.Topics) != 4 || log.Topics[0] != AnnouncementEventID {
+		return nil, errors.New("not an Announcement event")
+	}
+	values, err := announcerABI.Unpack("Announcement", log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Announcement data: %w", err)
+	}
+	a := &Announcement{
+		SchemeID:        log.Topics[1].Big(),
+		StealthAddress:  common.BytesToAddress(log.Topics[2].Bytes()),
+		Caller:          common.BytesToAddress(log.Topics[3].Bytes()),
+		EphemeralPubKey: values[0].([]byte),
+		Metadata:        values[1].([]byte),
+	}
+	if _, err := a.EphemeralPublicKey(); err != nil {
+		return nil, err
+	}
+	if len(a.Metadata) == 0 {
+		return nil, errors.New("announcement metadata has no view tag")
+	}
+	return a, nil
+}
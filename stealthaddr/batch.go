@@ -0,0 +1,59 @@
+package stealthaddr
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// DeriveBatch computes a stealth payment for each meta-address, each with its own fresh
+// ephemeral key, as a sender paying many recipients at once would. The scalar
+// multiplications are spread over one worker per CPU. The payments are returned in the
+// order of metas; if any derivation fails, DeriveBatch returns the error of the first
+// failing meta-address.
+func DeriveBatch(metas []*MetaAddress) ([]*StealthPayment, error) {
+	payments, i, err := deriveEach(len(metas), func(i int, r *EphemeralKey) (*StealthPayment, error) {
+		return ComputeStealthAddress(metas[i], r)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("meta-address %d: %w", i, err)
+	}
+	return payments, nil
+}
+
+// deriveEach calls pay with a fresh ephemeral key for each i in [0, n) on one worker per CPU
+// and returns the payments in order. If any call fails, it returns the first failing i and
+// its error.
+func deriveEach(n int, pay func(i int, r *EphemeralKey) (*StealthPayment, error)) ([]*StealthPayment, int, error) {
+	payments := make([]*StealthPayment, n)
+	errs := make([]error, n)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r, err := GenerateEphemeralKey()
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				payments[i], errs[i] = pay(i, r)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, i, err
+		}
+	}
+	return payments, 0, nil
+}
@@ -0,0 +1,112 @@
+package stealthaddr
+
+import (
+	"strings"
+	"testing"
+)
+
+// testMetas returns the meta-addresses of n fresh accounts, and the accounts.
+func testMetas(tb testing.TB, n int) ([]*MetaAddress, []*Account) {
+	tb.Helper()
+	metas := make([]*MetaAddress, n)
+	accounts := make([]*Account, n)
+	for i := range metas {
+		account, err := GenerateAccount()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		accounts[i], metas[i] = account, account.MetaAddress()
+	}
+	return metas, accounts
+}
+
+func TestDeriveBatch(t *testing.T) {
+	metas, accounts := testMetas(t, 50)
+	payments, err := DeriveBatch(metas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != len(metas) {
+		t.Fatalf("%d payments for %d meta-addresses", len(payments), len(metas))
+	}
+	ephemeral := make(map[string]int)
+	for i, p := range payments {
+		// payments[i] must pay metas[i], whose owner alone can find it.
+		found, err := DeriveStealthAddress(accounts[i].ViewingKey, metas[i].SpendingPubKey, p.EphemeralPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Address != p.Address || found.ViewTag != p.ViewTag {
+			t.Fatalf("payment %d is to %s, its recipient derives %s", i, p.Address, found.Address)
+		}
+		R := string(p.EphemeralPublicKey.Bytes())
+		if j, dup := ephemeral[R]; dup {
+			t.Fatalf("payments %d and %d share an ephemeral key", j, i)
+		}
+		ephemeral[R] = i
+	}
+}
+
+// TestDeriveEachOrder checks that deriveEach puts the result of every index in its place,
+// whichever worker computed it, and hands every index a fresh ephemeral key.
+func TestDeriveEachOrder(t *testing.T) {
+	metas, _ := testMetas(t, 50)
+	keys := make([]*EphemeralKey, len(metas))
+	payments, _, err := deriveEach(len(metas), func(i int, r *EphemeralKey) (*StealthPayment, error) {
+		keys[i] = r
+		return ComputeStealthAddress(metas[i], r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]int)
+	for i, p := range payments {
+		want, err := ComputeStealthAddress(metas[i], keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Address != want.Address {
+			t.Fatalf("payment %d is to %s, ComputeStealthAddress with its key gives %s", i, p.Address, want.Address)
+		}
+		if j, dup := seen[keys[i].PrivateKey.String()]; dup {
+			t.Fatalf("indices %d and %d got the same ephemeral key", j, i)
+		}
+		seen[keys[i].PrivateKey.String()] = i
+	}
+}
+
+func TestDeriveBatchReportsIndex(t *testing.T) {
+	metas, _ := testMetas(t, 10)
+	metas[6] = &MetaAddress{}
+	if _, err := DeriveBatch(metas); err == nil || !strings.HasPrefix(err.Error(), "meta-address 6:") {
+		t.Fatalf("error %v, want one for meta-address 6", err)
+	}
+}
+
+// BenchmarkDeriveBatch derives payments to 100 meta-addresses with DeriveBatch, and
+// BenchmarkDeriveLoop does the same one at a time. The batch only wins with more than one CPU.
+func BenchmarkDeriveBatch(b *testing.B) {
+	metas, _ := testMetas(b, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DeriveBatch(metas); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeriveLoop(b *testing.B) {
+	metas, _ := testMetas(b, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, meta := range metas {
+			r, err := GenerateEphemeralKey()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := ComputeStealthAddress(meta, r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package stealthaddr
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// The functions in this file derive several stealth addresses from one shared secret, for
+// example for a stream of payments or for change, where a single announcement of R covers
+// them all. Address k uses hash_k(S) = keccak256(S || k) instead of hash(S):
+//
+//	P_k = M + G * hash_k(S)
+//	p_k = m + hash_k(S)
+//
+// Every k, including 0, differs from the plain address of ComputeStealthAddress. The view
+// tag stays that of S, so a recipient that finds R with CheckAnnouncement or by its view
+// tag tries the indices it expects.
+
+// SharedSecretToScalarAt returns hash_k(S): keccak256 of the 33-byte compressed shared secret
+// followed by k as a 32-byte big-endian integer, reduced modulo the curve order.
+func SharedSecretToScalarAt(S Point, k uint64) (*big.Int, error) {
+	hashS := new(big.Int).SetBytes(crypto.Keccak256(S.Bytes(), encodeIndex(k)))
+	hashS.Mod(hashS, curve.Params().N)
+	if hashS.Sign() == 0 {
+		return nil, ErrZeroSharedSecretScalar
+	}
+	return hashS, nil
+}
+
+// ComputeStealthAddressAt is ComputeStealthAddress for stealth address k of the ephemeral
+// key r.
+func ComputeStealthAddressAt(meta *MetaAddress, r *EphemeralKey, k uint64) (*StealthPayment, error) {
+	if meta == nil || r == nil {
+		return nil, errors.New("missing meta-address or ephemeral key")
+	}
+	if err := validatePoints([]string{"spending public key", "viewing public key"}, meta.SpendingPubKey, meta.ViewingPubKey); err != nil {
+		return nil, err
+	}
+	if err := validateKeys([]string{"ephemeral key"}, r.PrivateKey); err != nil {
+		return nil, err
+	}
+	return stealthPaymentAt(meta.SpendingPubKey, SharedSecret(r.PrivateKey, meta.ViewingPubKey), r.PublicKey, k)
+}
+
+// DeriveStealthAddressAt is DeriveStealthAddress for stealth address k of the published R.
+func DeriveStealthAddressAt(viewingKey *big.Int, spendingPubKey, R Point, k uint64) (*StealthPayment, error) {
+	if err := validateKeys([]string{"viewing key"}, viewingKey); err != nil {
+		return nil, err
+	}
+	if err := validatePoints([]string{"spending public key", "ephemeral public key"}, spendingPubKey, R); err != nil {
+		return nil, err
+	}
+	return stealthPaymentAt(spendingPubKey, SharedSecret(viewingKey, R), R, k)
+}
+
+// DeriveStealthPrivateKeyAt is DeriveStealthPrivateKey for stealth address k of the
+// published R: p_k = m + hash_k(S) with S = v * R.
+func DeriveStealthPrivateKeyAt(spendingKey, viewingKey *big.Int, R Point, k uint64) (*big.Int, error) {
+	if err := validateKeys([]string{"spending key", "viewing key"}, spendingKey, viewingKey); err != nil {
+		return nil, err
+	}
+	if err := validatePoints([]string{"ephemeral public key"}, R); err != nil {
+		return nil, err
+	}
+	hashS, err := SharedSecretToScalarAt(SharedSecret(viewingKey, R), k)
+	if err != nil {
+		return nil, err
+	}
+	return stealthPrivateKey(spendingKey, hashS)
+}
+
+// encodeIndex returns k as a 32-byte big-endian integer.
+func encodeIndex(k uint64) []byte {
+	index := make([]byte, 32)
+	binary.BigEndian.PutUint64(index[24:], k)
+	return index
+}
+
+// stealthPaymentAt computes P_k = M + G * hash_k(S). The addresses of one S share it, so
+// the memo key of address k has k in it as well.
+func stealthPaymentAt(M, S, R Point, k uint64) (*StealthPayment, error) {
+	hashS, err := SharedSecretToScalarAt(S, k)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := stealthPaymentFor(M, S, R, hashS)
+	if err != nil {
+		return nil, err
+	}
+	payment.memoIndex = encodeIndex(k)
+	return payment, nil
+}
@@ -0,0 +1,46 @@
+package stealthaddr
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestIndexedAgree checks that for every index k the sender and the recipient derive the
+// same stealth address, that the recipient's private key controls it, and that distinct
+// indices of one ephemeral key give distinct addresses.
+func TestIndexedAgree(t *testing.T) {
+	rng := rand.New(rand.NewSource(277))
+	bob := &Account{SpendingKey: randomKey(rng), ViewingKey: randomKey(rng)}
+	r, err := NewEphemeralKey(randomKey(rng))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := bob.MetaAddress()
+
+	seen := make(map[string]uint64)
+	for _, k := range []uint64{0, 1, 255, 256, math.MaxUint64, rng.Uint64()} {
+		sent, err := ComputeStealthAddressAt(meta, r, k)
+		if err != nil {
+			t.Fatalf("k %d: %v", k, err)
+		}
+		found, err := DeriveStealthAddressAt(bob.ViewingKey, meta.SpendingPubKey, sent.EphemeralPublicKey, k)
+		if err != nil {
+			t.Fatalf("k %d: %v", k, err)
+		}
+		if found.Address != sent.Address {
+			t.Fatalf("k %d: recipient derives %s, sender %s", k, found.Address, sent.Address)
+		}
+		p, err := DeriveStealthPrivateKeyAt(bob.SpendingKey, bob.ViewingKey, sent.EphemeralPublicKey, k)
+		if err != nil {
+			t.Fatalf("k %d: %v", k, err)
+		}
+		if got := PublicKey(p).Address(); got != sent.Address {
+			t.Fatalf("k %d: private key controls %s, want %s", k, got, sent.Address)
+		}
+		if other, dup := seen[sent.Address.Hex()]; dup {
+			t.Fatalf("k %d and %d both give %s", other, k, sent.Address)
+		}
+		seen[sent.Address.Hex()] = k
+	}
+}
@@ -0,0 +1,103 @@
+package stealthaddr
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MaxMemoLength is the longest memo EncryptMemo accepts. Memos are meant for short context
+// such as an invoice id; every byte is paid for in the announcement's calldata.
+const MaxMemoLength = 128
+
+// memoNonceSize is the length of the random GCM nonce a sealed memo starts with.
+const memoNonceSize = 12
+
+// memoOverhead is the length of the nonce and the GCM authentication tag around a sealed
+// memo.
+const memoOverhead = memoNonceSize + 16
+
+// ErrNoMemo is returned by DecryptMemo for announcements that carry no memo.
+var ErrNoMemo = errors.New("announcement has no memo")
+
+// memoCipher returns AES-256-GCM keyed with keccak256(S || index || "memo"), where index is
+// empty for the plain payment of S and the 32-byte index k for stealth address k of
+// ComputeStealthAddressAt. Only the sender, who knows r, and the recipient, who knows v, can
+// compute S.
+func memoCipher(S Point, index []byte) (cipher.AEAD, error) {
+	if S.X == nil {
+		return nil, errors.New("payment has no shared secret")
+	}
+	block, err := aes.NewCipher(crypto.Keccak256(S.Bytes(), index, []byte("memo")))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptMemo encrypts memo to the recipient of the payment, with a key derived from the
+// payment's shared secret and a random nonce, so that encrypting again for the same payment
+// never reuses a nonce. The result, the nonce followed by the ciphertext, is what
+// BuildAnnouncementWithMemo appends to the metadata.
+func (p *StealthPayment) EncryptMemo(memo []byte) ([]byte, error) {
+	nonce := make([]byte, memoNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return p.sealMemo(nonce, memo)
+}
+
+// sealMemo encrypts memo with the given nonce and returns it after the nonce.
+func (p *StealthPayment) sealMemo(nonce, memo []byte) ([]byte, error) {
+	if len(memo) > MaxMemoLength {
+		return nil, fmt.Errorf("memo is %d bytes, at most %d are allowed", len(memo), MaxMemoLength)
+	}
+	aead, err := memoCipher(p.sharedSecret, p.memoIndex)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(append([]byte(nil), nonce...), nonce, memo, p.Address.Bytes()), nil
+}
+
+// DecryptMemo decrypts the memo of the announcement a of the payment, as found by
+// CheckAnnouncement. It returns ErrNoMemo if the metadata ends after the transfer details.
+func (p *StealthPayment) DecryptMemo(a *Announcement) ([]byte, error) {
+	if len(a.Metadata) <= transferMetadataLength {
+		return nil, ErrNoMemo
+	}
+	sealed := a.Metadata[transferMetadataLength:]
+	if len(sealed) < memoOverhead {
+		return nil, errors.New("memo is too short")
+	}
+	aead, err := memoCipher(p.sharedSecret, p.memoIndex)
+	if err != nil {
+		return nil, err
+	}
+	memo, err := aead.Open(nil, sealed[:memoNonceSize], sealed[memoNonceSize:], p.Address.Bytes())
+	if err != nil {
+		return nil, errors.New("memo cannot be decrypted with this payment's shared secret")
+	}
+	return memo, nil
+}
+
+// BuildAnnouncementWithMemo is BuildAnnouncement with memo encrypted to the recipient and
+// appended to the metadata after the transfer details, which must be given. Readers that do
+// not know about memos ignore the extra bytes, see DecodeMetadata.
+func BuildAnnouncementWithMemo(payment *StealthPayment, t *Transfer, memo []byte) (*Announcement, error) {
+	if t == nil {
+		return nil, errors.New("a memo needs the transfer details in the metadata")
+	}
+	sealed, err := payment.EncryptMemo(memo)
+	if err != nil {
+		return nil, err
+	}
+	a, err := BuildAnnouncement(payment, t)
+	if err != nil {
+		return nil, err
+	}
+	a.Metadata = append(a.Metadata, sealed...)
+	return a, nil
+}
@@ -0,0 +1,141 @@
+package stealthaddr
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// TestMemoKeystreamPerIndex checks that the indexed addresses of one ephemeral key, which
+// share S, never encrypt their memos with the same keystream even under the same nonce.
+// Sealing zeros exposes the keystream directly.
+func TestMemoKeystreamPerIndex(t *testing.T) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := GenerateEphemeralKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := bob.MetaAddress()
+	zeros := make([]byte, MaxMemoLength)
+	nonce := make([]byte, memoNonceSize)
+
+	payments := make([]*StealthPayment, 0, 9)
+	plain, err := ComputeStealthAddress(meta, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payments = append(payments, plain)
+	for k := uint64(0); k < 8; k++ {
+		p, err := ComputeStealthAddressAt(meta, r, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, p)
+	}
+
+	var keystreams [][]byte
+	for i, p := range payments {
+		sealed, err := p.sealMemo(nonce, zeros)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keystream := sealed[memoNonceSize : memoNonceSize+len(zeros)]
+		for j, other := range keystreams {
+			if bytes.Equal(keystream, other) {
+				t.Fatalf("payments %d and %d share a keystream", j, i)
+			}
+		}
+		keystreams = append(keystreams, keystream)
+	}
+}
+
+// TestMemoIndexedRoundTrip checks that the recipient decrypts the memo of address k, and
+// that the memo of one index does not open with the key of another.
+func TestMemoIndexedRoundTrip(t *testing.T) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := GenerateEphemeralKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memo := []byte("invoice 42")
+	sent, err := ComputeStealthAddressAt(bob.MetaAddress(), r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := BuildAnnouncementWithMemo(sent, ETHTransfer(big.NewInt(1)), memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := DeriveStealthAddressAt(bob.ViewingKey, bob.MetaAddress().SpendingPubKey, sent.EphemeralPublicKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := found.DecryptMemo(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, memo) {
+		t.Fatalf("memo %q, want %q", got, memo)
+	}
+
+	// Address 0 has the same S, and the announcement is rebound to its address so that
+	// only the key differs.
+	wrong, err := DeriveStealthAddressAt(bob.ViewingKey, bob.MetaAddress().SpendingPubKey, sent.EphemeralPublicKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong.Address = sent.Address
+	if _, err := wrong.DecryptMemo(a); err == nil {
+		t.Fatal("memo of address 1 decrypts with the key of address 0")
+	}
+}
+
+// TestMemoEncryptTwice checks that encrypting twice for the same payment, and so under the
+// same key, uses fresh nonces, and that both memos decrypt.
+func TestMemoEncryptTwice(t *testing.T) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := GenerateEphemeralKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := ComputeStealthAddress(bob.MetaAddress(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memo := []byte("invoice 42")
+	first, err := BuildAnnouncementWithMemo(payment, ETHTransfer(big.NewInt(1)), memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := BuildAnnouncementWithMemo(payment, ETHTransfer(big.NewInt(1)), memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first.Metadata, second.Metadata) {
+		t.Fatal("encrypting the same memo twice gives the same ciphertext")
+	}
+
+	found, err := DeriveStealthAddress(bob.ViewingKey, PublicKey(bob.SpendingKey), payment.EphemeralPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range []*Announcement{first, second} {
+		got, err := found.DecryptMemo(a)
+		if err != nil {
+			t.Fatalf("memo %d: %v", i, err)
+		}
+		if !bytes.Equal(got, memo) {
+			t.Fatalf("memo %d is %q, want %q", i, got, memo)
+		}
+	}
+}
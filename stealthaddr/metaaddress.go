@@ -0,0 +1,62 @@
+package stealthaddr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MetaAddressPrefix is the prefix of a stealth meta-address for Ethereum, as defined by EIP-5564.
+const MetaAddressPrefix = "st:eth:0x"
+
+// metaAddressLength is the length of an encoded meta-address: two compressed public keys.
+const metaAddressLength = 2 * 33
+
+// MetaAddress is the stealth meta-address a recipient publishes: the spending public key
+// M = G * m and the viewing public key V = G * v.
+type MetaAddress struct {
+	SpendingPubKey Point
+	ViewingPubKey  Point
+}
+
+// Bytes returns the 66-byte encoding of the meta-address: the compressed spending public key
+// followed by the compressed viewing public key. This is the form stored in the ERC-6538 registry.
+func (m *MetaAddress) Bytes() []byte {
+	return append(m.SpendingPubKey.Bytes(), m.ViewingPubKey.Bytes()...)
+}
+
+// String returns the meta-address in the st:eth:0x<spendingPubKey><viewingPubKey> form.
+func (m *MetaAddress) String() string {
+	return MetaAddressPrefix + hex.EncodeToString(m.Bytes())
+}
+
+// MetaAddressFromBytes decodes the 66-byte encoding returned by Bytes.
+func MetaAddressFromBytes(b []byte) (*MetaAddress, error) {
+	if len(b) != metaAddressLength {
+		return nil, fmt.Errorf("meta-address must be %d bytes, got %d", metaAddressLength, len(b))
+	}
+	spend, err := ParsePoint(b[:33])
+	if err != nil {
+		return nil, fmt.Errorf("invalid spending public key: %w", err)
+	}
+	view, err := ParsePoint(b[33:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid viewing public key: %w", err)
+	}
+	return &MetaAddress{SpendingPubKey: spend, ViewingPubKey: view}, nil
+}
+
+// ParseMetaAddress parses a meta-address in the st:eth:0x... form. The format has no
+// checksum, so besides the prefix and length it checks that both keys are valid points
+// on the curve.
+func ParseMetaAddress(s string) (*MetaAddress, error) {
+	if !strings.HasPrefix(s, MetaAddressPrefix) {
+		return nil, errors.New("meta-address must start with " + MetaAddressPrefix)
+	}
+	b, err := hex.DecodeString(s[len(MetaAddressPrefix):])
+	if err != nil {
+		return nil, fmt.Errorf("invalid meta-address hex: %w", err)
+	}
+	return MetaAddressFromBytes(b)
+}
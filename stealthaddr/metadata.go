@@ -0,0 +1,96 @@
+package stealthaddr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"math/big"
+)
+
+// The function identifiers EIP-5564 puts in announcement metadata to say what was sent.
+var (
+	// ETHSelector marks a plain ETH transfer.
+	ETHSelector = [4]byte{0xee, 0xee, 0xee, 0xee}
+	// ERC20TransferSelector is the selector of ERC-20 transfer(address,uint256).
+	ERC20TransferSelector = [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+	// ERC721SafeTransferFromSelector is the selector of ERC-721 safeTransferFrom(address,address,uint256).
+	ERC721SafeTransferFromSelector = [4]byte{0x42, 0x84, 0x2e, 0x0e}
+)
+
+// ETHToken is the token address EIP-5564 metadata uses for ETH.
+var ETHToken = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+// transferMetadataLength is the view tag, the selector, the token address and the amount.
+const transferMetadataLength = 1 + 4 + 20 + 32
+
+// Transfer describes what a stealth payment sent, as encoded in the announcement metadata
+// after the view tag.
+type Transfer struct {
+	// Selector is ETHSelector or the selector of the token function that was called.
+	Selector [4]byte
+	// Token is the token contract, or ETHToken for ETH.
+	Token common.Address
+	// Amount is the amount in wei or token units, or the token id of an ERC-721 token.
+	Amount *big.Int
+}
+
+// ETHTransfer returns the transfer of amount wei.
+func ETHTransfer(amount *big.Int) *Transfer {
+	return &Transfer{Selector: ETHSelector, Token: ETHToken, Amount: amount}
+}
+
+// ERC20Transfer returns the transfer of amount units of the ERC-20 token.
+func ERC20Transfer(token common.Address, amount *big.Int) *Transfer {
+	return &Transfer{Selector: ERC20TransferSelector, Token: token, Amount: amount}
+}
+
+// ERC721Transfer returns the transfer of the ERC-721 token tokenID.
+func ERC721Transfer(token common.Address, tokenID *big.Int) *Transfer {
+	return &Transfer{Selector: ERC721SafeTransferFromSelector, Token: token, Amount: tokenID}
+}
+
+// EncodeMetadata returns the announcement metadata for a payment with view tag viewTag:
+// the view tag followed, if t is not nil, by the selector, the token address and the
+// 32-byte amount. The amount must fit in a uint256.
+func EncodeMetadata(viewTag byte, t *Transfer) ([]byte, error) {
+	if t == nil {
+		return []byte{viewTag}, nil
+	}
+	if t.Amount == nil || t.Amount.Sign() < 0 || t.Amount.BitLen() > 256 {
+		return nil, errors.New("transfer amount must be a uint256")
+	}
+	metadata := make([]byte, transferMetadataLength)
+	metadata[0] = viewTag
+	copy(metadata[1:5], t.Selector[:])
+	copy(metadata[5:25], t.Token.Bytes())
+	math.ReadBits(t.Amount, metadata[25:57])
+	return metadata, nil
+}
+
+// DecodeMetadata decodes announcement metadata. The transfer is nil if the metadata holds
+// only the view tag; bytes after the amount are ignored.
+func DecodeMetadata(metadata []byte) (byte, *Transfer, error) {
+	if len(metadata) == 0 {
+		return 0, nil, errors.New("announcement metadata has no view tag")
+	}
+	if len(metadata) == 1 {
+		return metadata[0], nil, nil
+	}
+	if len(metadata) < transferMetadataLength {
+		return 0, nil, fmt.Errorf("announcement metadata must be 1 or at least %d bytes, got %d", transferMetadataLength, len(metadata))
+	}
+	t := &Transfer{
+		Token:  common.BytesToAddress(metadata[5:25]),
+		Amount: new(big.Int).SetBytes(metadata[25:57]),
+	}
+	copy(t.Selector[:], metadata[1:5])
+	return metadata[0], t, nil
+}
+
+// Transfer decodes what the payment of the announcement sent. It is nil if the metadata
+// holds only the view tag.
+func (a *Announcement) Transfer() (*Transfer, error) {
+	_, t, err := DecodeMetadata(a.Metadata)
+	return t, err
+}
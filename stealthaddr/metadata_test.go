@@ -0,0 +1,82 @@
+package stealthaddr
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	token := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	tests := []struct {
+		name     string
+		transfer *Transfer
+		want     string
+	}{
+		{"view tag only", nil, "0x2a"},
+		{"native", ETHTransfer(big.NewInt(1e18)),
+			"0x2aeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee0000000000000000000000000000000000000000000000000de0b6b3a7640000"},
+		{"native, max amount", ETHTransfer(maxUint256),
+			"0x2aeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"ERC-20", ERC20Transfer(token, big.NewInt(500)),
+			"0x2aa9059cbb6b175474e89094c44da98b954eedeac495271d0f00000000000000000000000000000000000000000000000000000000000001f4"},
+		{"ERC-721", ERC721Transfer(token, big.NewInt(7)),
+			"0x2a42842e0e6b175474e89094c44da98b954eedeac495271d0f0000000000000000000000000000000000000000000000000000000000000007"},
+	}
+	for _, tt := range tests {
+		metadata, err := EncodeMetadata(0x2a, tt.transfer)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := hexutil.Encode(metadata); got != tt.want {
+			t.Fatalf("%s: metadata %s, want %s", tt.name, got, tt.want)
+		}
+		// Bytes after the amount, such as a memo, do not change the decoding.
+		if tt.transfer != nil {
+			metadata = append(metadata, 1, 2, 3)
+		}
+		viewTag, transfer, err := DecodeMetadata(metadata)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if viewTag != 0x2a {
+			t.Fatalf("%s: view tag %#x, want 0x2a", tt.name, viewTag)
+		}
+		if (transfer == nil) != (tt.transfer == nil) {
+			t.Fatalf("%s: decoded transfer %+v, want %+v", tt.name, transfer, tt.transfer)
+		}
+		if transfer != nil && (transfer.Selector != tt.transfer.Selector || transfer.Token != tt.transfer.Token || transfer.Amount.Cmp(tt.transfer.Amount) != 0) {
+			t.Fatalf("%s: decoded transfer %+v, want %+v", tt.name, transfer, tt.transfer)
+		}
+	}
+}
+
+func TestEncodeMetadataRejectsBadAmounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount *big.Int
+	}{
+		{"nil", nil},
+		{"negative", big.NewInt(-1)},
+		{"2^256", new(big.Int).Lsh(big.NewInt(1), 256)},
+	}
+	for _, tt := range tests {
+		if metadata, err := EncodeMetadata(0x2a, ETHTransfer(tt.amount)); err == nil {
+			t.Errorf("%s amount: encoded as %x", tt.name, metadata)
+		}
+	}
+}
+
+func TestDecodeMetadataRejectsTruncated(t *testing.T) {
+	metadata, err := EncodeMetadata(0x2a, ETHTransfer(big.NewInt(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 2, len(metadata) - 1} {
+		if _, _, err := DecodeMetadata(metadata[:n]); err == nil {
+			t.Errorf("%d bytes of metadata decode", n)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package stealthaddr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Payer derives stealth payments to a single meta-address, for senders that pay the same
+// recipient repeatedly. It decodes and validates the recipient's public keys once, and can
+// compute payments ahead of time with Precompute so that Next only pops a ready one.
+//
+// The scalar multiplications themselves already run in libsecp256k1 through go-ethereum's
+// crypto.S256, which does not accept precomputed tables for an arbitrary point. A fixed-base
+// table for V built from the curve's affine Add is slower than a single ScalarMult there, so
+// the Payer saves work by moving whole derivations off the payment path instead.
+type Payer struct {
+	meta *MetaAddress
+
+	mu    sync.Mutex
+	ready []*StealthPayment
+}
+
+// NewPayer returns a payer for meta, after checking both of its public keys.
+func NewPayer(meta *MetaAddress) (*Payer, error) {
+	if meta == nil {
+		return nil, errors.New("missing meta-address")
+	}
+	if err := validatePoints([]string{"spending public key", "viewing public key"}, meta.SpendingPubKey, meta.ViewingPubKey); err != nil {
+		return nil, err
+	}
+	return &Payer{meta: meta}, nil
+}
+
+// MetaAddress returns the meta-address the payer pays.
+func (p *Payer) MetaAddress() *MetaAddress {
+	return p.meta
+}
+
+// Pay is ComputeStealthAddress for the payer's meta-address, without checking its keys again.
+func (p *Payer) Pay(r *EphemeralKey) (*StealthPayment, error) {
+	if r == nil {
+		return nil, errors.New("missing ephemeral key")
+	}
+	if err := validateKeys([]string{"ephemeral key"}, r.PrivateKey); err != nil {
+		return nil, err
+	}
+	S := SharedSecret(r.PrivateKey, p.meta.ViewingPubKey)
+	return stealthPayment(p.meta.SpendingPubKey, S, r.PublicKey)
+}
+
+// Next returns a payment with a fresh ephemeral key: a precomputed one if there is any left,
+// otherwise one derived now. A payment is never returned twice.
+func (p *Payer) Next() (*StealthPayment, error) {
+	p.mu.Lock()
+	if n := len(p.ready); n > 0 {
+		payment := p.ready[n-1]
+		p.ready[n-1] = nil
+		p.ready = p.ready[:n-1]
+		p.mu.Unlock()
+		return payment, nil
+	}
+	p.mu.Unlock()
+
+	r, err := GenerateEphemeralKey()
+	if err != nil {
+		return nil, err
+	}
+	return p.Pay(r)
+}
+
+// Precompute derives n more payments on all CPUs with Pay and keeps them for Next. The
+// payments hold their shared secrets, which link them to the recipient, so a payer with
+// precomputed payments should be kept as private as the sender's own key.
+func (p *Payer) Precompute(n int) error {
+	if n < 0 {
+		return fmt.Errorf("cannot precompute %d payments", n)
+	}
+	payments, _, err := deriveEach(n, func(_ int, r *EphemeralKey) (*StealthPayment, error) {
+		return p.Pay(r)
+	})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.ready = append(p.ready, payments...)
+	p.mu.Unlock()
+	return nil
+}
+
+// Precomputed returns the number of payments Next can return without deriving one.
+func (p *Payer) Precomputed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.ready)
+}
@@ -0,0 +1,120 @@
+package stealthaddr
+
+import (
+	"testing"
+)
+
+func newTestPayer(tb testing.TB) (*Account, *Payer) {
+	tb.Helper()
+	bob, err := GenerateAccount()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	payer, err := NewPayer(bob.MetaAddress())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return bob, payer
+}
+
+func TestPayer(t *testing.T) {
+	bob, payer := newTestPayer(t)
+	if err := payer.Precompute(3); err != nil {
+		t.Fatal(err)
+	}
+	if n := payer.Precomputed(); n != 3 {
+		t.Fatalf("%d payments precomputed, want 3", n)
+	}
+	// Three precomputed payments and two derived on the spot, all distinct and all Bob's.
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		payment, err := payer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[payment.Address.Hex()] {
+			t.Fatalf("payment %d repeats %s", i, payment.Address)
+		}
+		seen[payment.Address.Hex()] = true
+		found, err := DeriveStealthAddress(bob.ViewingKey, PublicKey(bob.SpendingKey), payment.EphemeralPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Address != payment.Address {
+			t.Fatalf("payment %d is to %s, Bob derives %s", i, payment.Address, found.Address)
+		}
+	}
+	if n := payer.Precomputed(); n != 0 {
+		t.Fatalf("%d payments left, want 0", n)
+	}
+}
+
+func TestPayerRejectsBadInput(t *testing.T) {
+	_, payer := newTestPayer(t)
+	if err := payer.Precompute(-1); err == nil {
+		t.Error("Precompute(-1) succeeds")
+	}
+	if err := payer.Precompute(0); err != nil {
+		t.Errorf("Precompute(0): %v", err)
+	}
+	if _, err := payer.Pay(nil); err == nil {
+		t.Error("Pay(nil) succeeds")
+	}
+	if _, err := NewPayer(&MetaAddress{}); err == nil {
+		t.Error("NewPayer accepts an empty meta-address")
+	}
+}
+
+// BenchmarkPayNaive pays a meta-address the way a sender without a Payer does: parse the
+// meta-address string, then derive with ComputeStealthAddress.
+func BenchmarkPayNaive(b *testing.B) {
+	bob, _ := newTestPayer(b)
+	s := bob.MetaAddress().String()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		meta, err := ParseMetaAddress(s)
+		if err != nil {
+			b.Fatal(err)
+		}
+		r, err := GenerateEphemeralKey()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ComputeStealthAddress(meta, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPayerNext derives every payment on the spot with Next.
+func BenchmarkPayerNext(b *testing.B) {
+	_, payer := newTestPayer(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := payer.Next(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPayerNextPrecomputed takes every payment from a pool filled by Precompute, as
+// while the sender is idle. Deriving b.N payments would take far longer than popping them,
+// so a pool of poolSize is refilled with the same payments whenever it runs dry; the copy
+// costs well under a nanosecond per payment.
+func BenchmarkPayerNextPrecomputed(b *testing.B) {
+	const poolSize = 1000
+	_, payer := newTestPayer(b)
+	if err := payer.Precompute(poolSize); err != nil {
+		b.Fatal(err)
+	}
+	pool := append([]*StealthPayment(nil), payer.ready...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(payer.ready) == 0 {
+			payer.ready = append(payer.ready, pool...)
+		}
+		if _, err := payer.Next(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
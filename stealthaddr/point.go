@@ -0,0 +1,80 @@
+// Package stealthaddr implements the stealth addresses described in vitalik's post
+// "An incomplete guide to stealth addresses" (https://vitalik.ca/general/2023/01/20/stealth.html).
+package stealthaddr
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// curve is go-ethereum's secp256k1 implementation. It is backed by libsecp256k1
+// when cgo is enabled and by a pure-Go implementation otherwise (CGO_ENABLED=0),
+// and both produce identical results.
+var curve = crypto.S256()
+
+// Point is a point on the secp256k1 curve in affine coordinates.
+type Point struct {
+	X, Y *big.Int
+}
+
+// PublicKey returns the public key G * k of the private key k.
+func PublicKey(k *big.Int) Point {
+	x, y := curve.ScalarBaseMult(k.Bytes())
+	return Point{X: x, Y: y}
+}
+
+// IsInfinity reports whether p is the point at infinity, which the curve represents as (0, 0).
+func (p Point) IsInfinity() bool {
+	return p.X.Sign() == 0 && p.Y.Sign() == 0
+}
+
+// Validate reports an error unless p is a point on the curve other than the point at infinity.
+func (p Point) Validate() error {
+	if p.X == nil || p.Y == nil || p.IsInfinity() {
+		return errors.New("point is the point at infinity")
+	}
+	if !curve.IsOnCurve(p.X, p.Y) {
+		return errors.New("point is not on the curve")
+	}
+	return nil
+}
+
+// Bytes returns the 33-byte compressed SEC1 encoding of p.
+func (p Point) Bytes() []byte {
+	return crypto.CompressPubkey(&ecdsa.PublicKey{Curve: curve, X: p.X, Y: p.Y})
+}
+
+// ParsePoint decodes a 33-byte compressed SEC1 point, as returned by Bytes.
+// It rejects any other length or prefix, and X coordinates that are not on the curve.
+func ParsePoint(b []byte) (Point, error) {
+	if len(b) != 33 {
+		return Point{}, fmt.Errorf("compressed point must be 33 bytes, got %d", len(b))
+	}
+	if b[0] != 0x02 && b[0] != 0x03 {
+		return Point{}, fmt.Errorf("invalid compressed point prefix 0x%02x", b[0])
+	}
+	key, err := crypto.DecompressPubkey(b)
+	if err != nil {
+		return Point{}, errors.New("point is not on the curve")
+	}
+	p := Point{X: key.X, Y: key.Y}
+	if err := p.Validate(); err != nil {
+		return Point{}, err
+	}
+	return p, nil
+}
+
+// Address returns the Ethereum address of the public key p: the last 20 bytes of
+// keccak256(X || Y). It hashes the coordinates directly rather than going through an
+// ecdsa.PublicKey as crypto.PubkeyToAddress does.
+func (p Point) Address() common.Address {
+	var buf [64]byte
+	math.ReadBits(p.X, buf[:32])
+	math.ReadBits(p.Y, buf[32:])
+	return common.BytesToAddress(crypto.Keccak256(buf[:])[12:])
+}
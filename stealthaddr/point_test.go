@@ -0,0 +1,48 @@
+package stealthaddr
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/rand"
+	"testing"
+)
+
+// TestPointAddress checks Point.Address against crypto.PubkeyToAddress, including keys
+// whose coordinates have leading zero bytes.
+func TestPointAddress(t *testing.T) {
+	rng := rand.New(rand.NewSource(134))
+	short := 0
+	for i := 0; i < 1000; i++ {
+		P := PublicKey(randomKey(rng))
+		if len(P.X.Bytes()) < 32 || len(P.Y.Bytes()) < 32 {
+			short++
+		}
+		want := crypto.PubkeyToAddress(ecdsa.PublicKey{Curve: curve, X: P.X, Y: P.Y})
+		if got := P.Address(); got != want {
+			t.Fatalf("case %d: Address %s, PubkeyToAddress %s", i, got, want)
+		}
+	}
+	if short == 0 {
+		t.Fatal("no coordinate with a leading zero byte was tested")
+	}
+}
+
+// BenchmarkPointAddress and BenchmarkPubkeyToAddress compare the address of a public key
+// computed from its coordinates with the one that goes through an ecdsa.PublicKey.
+func BenchmarkPointAddress(b *testing.B) {
+	P := PublicKey(randomKey(rand.New(rand.NewSource(134))))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		P.Address()
+	}
+}
+
+func BenchmarkPubkeyToAddress(b *testing.B) {
+	P := PublicKey(randomKey(rand.New(rand.NewSource(134))))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		crypto.PubkeyToAddress(ecdsa.PublicKey{Curve: curve, X: P.X, Y: P.Y})
+	}
+}
@@ -0,0 +1,95 @@
+package stealthaddr
+
+import (
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// CheckAnnouncement reports whether the announcement a is a payment to the owner of the
+// viewing key v and spending public key M, and if so returns the payment. It compares the
+// view tag first, so for announcements that are not for the recipient it costs a single
+// scalar multiplication. Announcements of other schemes are never a match.
+func CheckAnnouncement(viewingKey *big.Int, spendingPubKey Point, a *Announcement) (*StealthPayment, bool, error) {
+	if err := validateScanKeys(viewingKey, spendingPubKey); err != nil {
+		return nil, false, err
+	}
+	return checkAnnouncement(viewingKey, spendingPubKey, a)
+}
+
+// validateScanKeys checks the keys of a recipient before scanning with them.
+func validateScanKeys(viewingKey *big.Int, spendingPubKey Point) error {
+	if err := validateKeys([]string{"viewing key"}, viewingKey); err != nil {
+		return err
+	}
+	return validatePoints([]string{"spending public key"}, spendingPubKey)
+}
+
+// checkAnnouncement is CheckAnnouncement for keys already checked with validateScanKeys.
+func checkAnnouncement(viewingKey *big.Int, spendingPubKey Point, a *Announcement) (*StealthPayment, bool, error) {
+	if a == nil || a.SchemeID == nil || a.SchemeID.Cmp(big.NewInt(SchemeIDSecp256k1)) != 0 || len(a.Metadata) == 0 {
+		return nil, false, nil
+	}
+	R, err := a.EphemeralPublicKey()
+	if err != nil {
+		return nil, false, err
+	}
+	S := SharedSecret(viewingKey, R)
+	if ViewTag(S) != a.Metadata[0] {
+		return nil, false, nil
+	}
+	payment, err := stealthPayment(spendingPubKey, S, R)
+	if err != nil {
+		return nil, false, err
+	}
+	// One in 256 announcements for other recipients passes the view tag check.
+	if payment.Address != a.StealthAddress {
+		return nil, false, nil
+	}
+	return payment, true, nil
+}
+
+// ScanMatch is an announcement found by ScanBatch.
+type ScanMatch struct {
+	// Index is the position of the announcement in the slice passed to ScanBatch.
+	Index   int
+	Payment *StealthPayment
+}
+
+// ScanBatch runs CheckAnnouncement on every announcement, spread over one worker per CPU,
+// and returns the matches in the order of announcements. The keys are checked once up
+// front, and an invalid one is an error. Nil entries and announcements with an invalid
+// ephemeral public key are skipped, as they cannot be payments to anyone.
+func ScanBatch(viewingKey *big.Int, spendingPubKey Point, announcements []*Announcement) ([]ScanMatch, error) {
+	if err := validateScanKeys(viewingKey, spendingPubKey); err != nil {
+		return nil, err
+	}
+	payments := make([]*StealthPayment, len(announcements))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if payment, ok, err := checkAnnouncement(viewingKey, spendingPubKey, announcements[i]); err == nil && ok {
+					payments[i] = payment
+				}
+			}
+		}()
+	}
+	for i := range announcements {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var matches []ScanMatch
+	for i, payment := range payments {
+		if payment != nil {
+			matches = append(matches, ScanMatch{Index: i, Payment: payment})
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,111 @@
+package stealthaddr
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testAnnouncements returns n announcements of which those at the indices in ours pay bob
+// and the rest pay fresh recipients.
+func testAnnouncements(tb testing.TB, bob *Account, n int, ours ...int) []*Announcement {
+	tb.Helper()
+	mine := make(map[int]bool)
+	for _, i := range ours {
+		mine[i] = true
+	}
+	announcements := make([]*Announcement, n)
+	for i := range announcements {
+		meta := bob.MetaAddress()
+		if !mine[i] {
+			other, err := GenerateAccount()
+			if err != nil {
+				tb.Fatal(err)
+			}
+			meta = other.MetaAddress()
+		}
+		r, err := GenerateEphemeralKey()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		payment, err := ComputeStealthAddress(meta, r)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if announcements[i], err = BuildAnnouncement(payment, ETHTransfer(big.NewInt(int64(i)))); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return announcements
+}
+
+func TestScanBatch(t *testing.T) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcements := testAnnouncements(t, bob, 100, 3, 42, 50, 99)
+	// A garbage announcement is skipped, not an error.
+	announcements[7].EphemeralPubKey = []byte{2, 1}
+	// So is a payment to bob announced under another scheme id, and a nil entry.
+	announcements[50].SchemeID = big.NewInt(2)
+	announcements[60] = nil
+
+	matches, err := ScanBatch(bob.ViewingKey, PublicKey(bob.SpendingKey), announcements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{3, 42, 99}
+	if len(matches) != len(want) {
+		t.Fatalf("%d matches, want %d", len(matches), len(want))
+	}
+	for i, m := range matches {
+		if m.Index != want[i] || m.Payment.Address != announcements[m.Index].StealthAddress {
+			t.Fatalf("match %d is announcement %d for %s, want announcement %d", i, m.Index, m.Payment.Address, want[i])
+		}
+	}
+}
+
+func TestScanBatchInvalidKeys(t *testing.T) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcements := testAnnouncements(t, bob, 4, 0)
+	M := PublicKey(bob.SpendingKey)
+	tests := []struct {
+		name           string
+		viewingKey     *big.Int
+		spendingPubKey Point
+	}{
+		{"zero viewing key", new(big.Int), M},
+		{"nil viewing key", nil, M},
+		{"viewing key N", new(big.Int).Set(curve.Params().N), M},
+		{"spending public key off the curve", bob.ViewingKey, Point{X: big.NewInt(1), Y: big.NewInt(1)}},
+		{"missing spending public key", bob.ViewingKey, Point{}},
+	}
+	for _, tt := range tests {
+		if _, err := ScanBatch(tt.viewingKey, tt.spendingPubKey, announcements); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+// BenchmarkScanBatch measures how many announcements per second a recipient scans, with
+// one in a hundred addressed to it.
+func BenchmarkScanBatch(b *testing.B) {
+	bob, err := GenerateAccount()
+	if err != nil {
+		b.Fatal(err)
+	}
+	announcements := testAnnouncements(b, bob, 1000, 0, 100, 200, 300, 400, 500, 600, 700, 800, 900)
+	M := PublicKey(bob.SpendingKey)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if _, err := ScanBatch(bob.ViewingKey, M, announcements); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(announcements))/time.Since(start).Seconds(), "announcements/s")
+}
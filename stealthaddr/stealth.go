@@ -0,0 +1,259 @@
+package stealthaddr
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// Account holds a recipient's two private keys. The spending key m controls the funds;
+// the viewing key v is only needed to find incoming payments, so it can be handed to a
+// scanning service without giving that service the ability to spend.
+type Account struct {
+	SpendingKey *big.Int
+	ViewingKey  *big.Int
+}
+
+// ValidateKey reports an error unless k is usable as a private key, that is 0 < k < N.
+func ValidateKey(k *big.Int) error {
+	if k == nil || k.Sign() <= 0 {
+		return errors.New("private key must be positive")
+	}
+	if k.Cmp(curve.Params().N) >= 0 {
+		return errors.New("private key must be less than the curve order")
+	}
+	return nil
+}
+
+// GenerateKey returns a private key drawn uniformly from [1, N-1] using crypto/rand.
+func GenerateKey() (*big.Int, error) {
+	max := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	k, err := rand.Int(rand.Reader, max) // [0, N-2]
+	if err != nil {
+		return nil, err
+	}
+	k.Add(k, big.NewInt(1))
+	if err := ValidateKey(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GenerateAccount generates a fresh spending key and viewing key.
+func GenerateAccount() (*Account, error) {
+	spend, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	view, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{SpendingKey: spend, ViewingKey: view}, nil
+}
+
+// MetaAddress returns the stealth meta-address of the account.
+func (a *Account) MetaAddress() *MetaAddress {
+	return &MetaAddress{
+		SpendingPubKey: PublicKey(a.SpendingKey),
+		ViewingPubKey:  PublicKey(a.ViewingKey),
+	}
+}
+
+// StealthKey returns the private key of the stealth address announced with the ephemeral
+// public key R, ready to sign transactions from that address.
+func (a *Account) StealthKey(R Point) (*ecdsa.PrivateKey, error) {
+	p, err := DeriveStealthPrivateKey(a.SpendingKey, a.ViewingKey, R)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(p, 32))
+}
+
+// EphemeralKey is the one-time key pair a sender generates for a payment: r and R = G * r.
+type EphemeralKey struct {
+	PrivateKey *big.Int
+	PublicKey  Point
+}
+
+// NewEphemeralKey returns the ephemeral key pair for the private key r, which must be in [1, N-1].
+func NewEphemeralKey(r *big.Int) (*EphemeralKey, error) {
+	if err := ValidateKey(r); err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	return &EphemeralKey{PrivateKey: r, PublicKey: PublicKey(r)}, nil
+}
+
+// GenerateEphemeralKey generates a fresh ephemeral key pair. Senders must use a new one
+// for every payment: reusing r links the payments and, with the same meta-address,
+// reuses the stealth address.
+func GenerateEphemeralKey() (*EphemeralKey, error) {
+	r, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewEphemeralKey(r)
+}
+
+// StealthPayment is what a sender derives for a single payment.
+type StealthPayment struct {
+	// Address is the stealth address the funds are sent to.
+	Address common.Address
+	// PublicKey is the stealth public key P that Address is derived from.
+	PublicKey Point
+	// EphemeralPublicKey is R, which the sender publishes so the recipient can find the payment.
+	EphemeralPublicKey Point
+	// ViewTag is published alongside R to let the recipient skip most payments cheaply.
+	ViewTag byte
+
+	// sharedSecret is S, kept to derive the memo key. It is unexported so that it is never
+	// serialized along with the payment.
+	sharedSecret Point
+	// memoIndex is the encoded index k of an indexed payment, which goes into its memo key,
+	// and nil for the plain payment of S.
+	memoIndex []byte
+}
+
+// SharedSecret returns S = k * P. The sender computes S = r * V and the recipient
+// computes the same S = v * R. It does not validate its inputs; the derivation functions
+// below check k with ValidateKey and P with Point.Validate before calling it.
+func SharedSecret(k *big.Int, P Point) Point {
+	x, y := curve.ScalarMult(P.X, P.Y, k.Bytes())
+	return Point{X: x, Y: y}
+}
+
+// ErrZeroSharedSecretScalar is returned in the negligible case where hash(S) reduces to zero,
+// which would make the stealth address equal the spending public key.
+var ErrZeroSharedSecretScalar = errors.New("shared secret hashes to zero")
+
+// hashSharedSecret returns keccak256 of the compressed encoding of S.
+func hashSharedSecret(S Point) []byte {
+	return crypto.Keccak256(S.Bytes())
+}
+
+// SharedSecretToScalar returns hash(S): keccak256 of the 33-byte compressed shared secret,
+// reduced modulo the curve order. This matches the EIP-5564 reference implementations, so
+// stealth addresses derived here agree with other wallets.
+func SharedSecretToScalar(S Point) (*big.Int, error) {
+	hashS := new(big.Int).SetBytes(hashSharedSecret(S))
+	hashS.Mod(hashS, curve.Params().N)
+	if hashS.Sign() == 0 {
+		return nil, ErrZeroSharedSecretScalar
+	}
+	return hashS, nil
+}
+
+// stealthPayment computes the stealth public key P = M + G * hash(S) for the spending
+// public key M and the shared secret S.
+func stealthPayment(M, S, R Point) (*StealthPayment, error) {
+	hashS, err := SharedSecretToScalar(S)
+	if err != nil {
+		return nil, err
+	}
+	return stealthPaymentFor(M, S, R, hashS)
+}
+
+// stealthPaymentFor computes P = M + G * hashS, where hashS is hash(S) or one of the indexed
+// hashes of SharedSecretToScalarAt.
+func stealthPaymentFor(M, S, R Point, hashS *big.Int) (*StealthPayment, error) {
+	GS := PublicKey(hashS)
+	Px, Py := curve.Add(M.X, M.Y, GS.X, GS.Y)
+	P := Point{X: Px, Y: Py}
+
+	// If M == -G * hash(S) the sum is the point at infinity. That is not a valid
+	// public key, so it must never be turned into an address.
+	if P.IsInfinity() {
+		return nil, errors.New("stealth public key is the point at infinity")
+	}
+
+	return &StealthPayment{
+		Address:            P.Address(),
+		PublicKey:          P,
+		EphemeralPublicKey: R,
+		ViewTag:            ViewTag(S),
+		sharedSecret:       S,
+	}, nil
+}
+
+// validateKeys checks that every key is in [1, N-1], naming the first invalid one.
+func validateKeys(names []string, keys ...*big.Int) error {
+	for i, k := range keys {
+		if err := ValidateKey(k); err != nil {
+			return fmt.Errorf("invalid %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// validatePoints checks that every point is on the curve and not the point at infinity,
+// naming the first invalid one.
+func validatePoints(names []string, points ...Point) error {
+	for i, p := range points {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// ComputeStealthAddress is the sender's side of the scheme: it computes the shared
+// secret S = V * r and the stealth public key P = M + G * hash(S).
+func ComputeStealthAddress(meta *MetaAddress, r *EphemeralKey) (*StealthPayment, error) {
+	if meta == nil || r == nil {
+		return nil, errors.New("missing meta-address or ephemeral key")
+	}
+	if err := validatePoints([]string{"spending public key", "viewing public key"}, meta.SpendingPubKey, meta.ViewingPubKey); err != nil {
+		return nil, err
+	}
+	if err := validateKeys([]string{"ephemeral key"}, r.PrivateKey); err != nil {
+		return nil, err
+	}
+	S := SharedSecret(r.PrivateKey, meta.ViewingPubKey)
+	return stealthPayment(meta.SpendingPubKey, S, r.PublicKey)
+}
+
+// DeriveStealthAddress is the recipient's view-only side of the scheme: from the viewing key v,
+// the spending public key M and a published R it recomputes the payment the sender derived.
+// It needs no spending key, so it can run on a scanning service.
+func DeriveStealthAddress(viewingKey *big.Int, spendingPubKey, R Point) (*StealthPayment, error) {
+	if err := validateKeys([]string{"viewing key"}, viewingKey); err != nil {
+		return nil, err
+	}
+	if err := validatePoints([]string{"spending public key", "ephemeral public key"}, spendingPubKey, R); err != nil {
+		return nil, err
+	}
+	return stealthPayment(spendingPubKey, SharedSecret(viewingKey, R), R)
+}
+
+// DeriveStealthPrivateKey is the recipient's spending side of the scheme: it computes the
+// private key p = m + hash(S) of the stealth address, where S = v * R. Only the holder of
+// the spending key m can do this.
+func DeriveStealthPrivateKey(spendingKey, viewingKey *big.Int, R Point) (*big.Int, error) {
+	if err := validateKeys([]string{"spending key", "viewing key"}, spendingKey, viewingKey); err != nil {
+		return nil, err
+	}
+	if err := validatePoints([]string{"ephemeral public key"}, R); err != nil {
+		return nil, err
+	}
+	hashS, err := SharedSecretToScalar(SharedSecret(viewingKey, R))
+	if err != nil {
+		return nil, err
+	}
+	return stealthPrivateKey(spendingKey, hashS)
+}
+
+// stealthPrivateKey returns p = m + hashS mod N for the spending key m.
+func stealthPrivateKey(spendingKey, hashS *big.Int) (*big.Int, error) {
+	p := new(big.Int).Add(spendingKey, hashS) // p = m + hash(S)
+	p.Mod(p, curve.Params().N)                // p = p % N, private key must be less than the order of the curve
+	// p is zero exactly when P is the point at infinity, which stealthPayment rejects too.
+	if p.Sign() == 0 {
+		return nil, errors.New("stealth private key is zero")
+	}
+	return p, nil
+}
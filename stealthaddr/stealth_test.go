@@ -0,0 +1,101 @@
+package stealthaddr
+
+import (
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randomKey returns a key in [1, N-1] drawn from rng.
+func randomKey(rng *rand.Rand) *big.Int {
+	max := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	k := new(big.Int).Rand(rng, max)
+	return k.Add(k, big.NewInt(1))
+}
+
+// TestStealthKeySigns derives the stealth address and private key for random keys, signs a
+// random digest with the private key and checks that the signature recovers to the stealth
+// address. That catches a missing mod N, encoding and parity bugs alike. The seed is fixed
+// so that a failure can be reproduced.
+func TestStealthKeySigns(t *testing.T) {
+	const cases = 1000
+	rng := rand.New(rand.NewSource(5564))
+	for i := 0; i < cases; i++ {
+		bob := &Account{SpendingKey: randomKey(rng), ViewingKey: randomKey(rng)}
+		r, err := NewEphemeralKey(randomKey(rng))
+		if err != nil {
+			t.Fatal(err)
+		}
+		payment, err := ComputeStealthAddress(bob.MetaAddress(), r)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		found, err := DeriveStealthAddress(bob.ViewingKey, PublicKey(bob.SpendingKey), payment.EphemeralPublicKey)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if found.Address != payment.Address {
+			t.Fatalf("case %d: recipient derives %s, sender %s", i, found.Address, payment.Address)
+		}
+		p, err := DeriveStealthPrivateKey(bob.SpendingKey, bob.ViewingKey, payment.EphemeralPublicKey)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		key, err := crypto.ToECDSA(math.PaddedBigBytes(p, 32))
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+
+		digest := make([]byte, 32)
+		rng.Read(digest)
+		sig, err := crypto.Sign(digest, key)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		signer, err := crypto.SigToPub(digest, sig)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if got := crypto.PubkeyToAddress(*signer); got != payment.Address {
+			t.Fatalf("case %d: signature recovers to %s, want stealth address %s", i, got, payment.Address)
+		}
+	}
+}
+
+// TestStealthPublicKeyAtInfinity crafts a spending key m = -hash(S) for a given ephemeral key,
+// so that P = M + G * hash(S) is the point at infinity, and checks that neither side turns
+// it into an address or a key.
+func TestStealthPublicKeyAtInfinity(t *testing.T) {
+	rng := rand.New(rand.NewSource(107))
+	viewingKey := randomKey(rng)
+	r, err := NewEphemeralKey(randomKey(rng))
+	if err != nil {
+		t.Fatal(err)
+	}
+	S := SharedSecret(r.PrivateKey, PublicKey(viewingKey))
+	hashS, err := SharedSecretToScalar(S)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spendingKey := new(big.Int).Sub(curve.Params().N, hashS)
+	M := PublicKey(spendingKey)
+	if GS := PublicKey(hashS); GS.X.Cmp(M.X) != 0 || GS.Y.Cmp(M.Y) == 0 {
+		t.Fatal("M is not -G * hash(S)")
+	}
+
+	if _, err := stealthPaymentFor(M, S, r.PublicKey, hashS); err == nil {
+		t.Error("stealthPaymentFor accepts P at infinity")
+	}
+	meta := &MetaAddress{SpendingPubKey: M, ViewingPubKey: PublicKey(viewingKey)}
+	if payment, err := ComputeStealthAddress(meta, r); err == nil {
+		t.Errorf("sender derives %s from P at infinity", payment.Address)
+	}
+	if payment, err := DeriveStealthAddress(viewingKey, M, r.PublicKey); err == nil {
+		t.Errorf("recipient derives %s from P at infinity", payment.Address)
+	}
+	if _, err := DeriveStealthPrivateKey(spendingKey, viewingKey, r.PublicKey); err == nil {
+		t.Error("recipient derives a private key for P at infinity")
+	}
+}
@@ -0,0 +1,39 @@
+package stealthaddr
+
+import (
+	"math/big"
+)
+
+// WatchOnlyAccount is the part of an Account a scanning service needs: the viewing key v and
+// the spending public key M. It can find the recipient's payments but not spend them.
+type WatchOnlyAccount struct {
+	ViewingKey     *big.Int
+	SpendingPubKey Point
+}
+
+// NewWatchOnlyAccount returns the watch-only account of the viewing key v and the spending
+// public key M, after checking both.
+func NewWatchOnlyAccount(viewingKey *big.Int, spendingPubKey Point) (*WatchOnlyAccount, error) {
+	if err := validateKeys([]string{"viewing key"}, viewingKey); err != nil {
+		return nil, err
+	}
+	if err := validatePoints([]string{"spending public key"}, spendingPubKey); err != nil {
+		return nil, err
+	}
+	return &WatchOnlyAccount{ViewingKey: viewingKey, SpendingPubKey: spendingPubKey}, nil
+}
+
+// WatchOnly returns the watch-only part of the account, to hand to a scanning service.
+func (a *Account) WatchOnly() *WatchOnlyAccount {
+	return &WatchOnlyAccount{ViewingKey: a.ViewingKey, SpendingPubKey: PublicKey(a.SpendingKey)}
+}
+
+// MetaAddress returns the stealth meta-address of the account.
+func (w *WatchOnlyAccount) MetaAddress() *MetaAddress {
+	return &MetaAddress{SpendingPubKey: w.SpendingPubKey, ViewingPubKey: PublicKey(w.ViewingKey)}
+}
+
+// CheckAnnouncement is CheckAnnouncement with the keys of w.
+func (w *WatchOnlyAccount) CheckAnnouncement(a *Announcement) (*StealthPayment, bool, error) {
+	return CheckAnnouncement(w.ViewingKey, w.SpendingPubKey, a)
+}
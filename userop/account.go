@@ -0,0 +1,243 @@
+package userop
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"stealth/internal/txutil"
+	"strings"
+)
+
+// SimpleAccountFactory is the address of the eth-infinitism SimpleAccountFactory for
+// EntryPoint v0.6.
+var SimpleAccountFactory = common.HexToAddress("0x9406Cc6185a346906296840746125a0E44976454")
+
+// Backend is the part of an Ethereum client the sweeper uses. *ethclient.Client implements it.
+type Backend interface {
+	bind.ContractCaller
+	bind.ContractTransactor
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// accountABI holds the functions of the factory, the account, the EntryPoint and ERC-20
+// tokens that a sweep calls.
+const accountABI = `[
+	{"type":"function","name":"getAddress","stateMutability":"view","outputs":[{"name":"","type":"address"}],"inputs":[
+		{"name":"owner","type":"address"},
+		{"name":"salt","type":"uint256"}
+	]},
+	{"type":"function","name":"createAccount","stateMutability":"nonpayable","outputs":[{"name":"","type":"address"}],"inputs":[
+		{"name":"owner","type":"address"},
+		{"name":"salt","type":"uint256"}
+	]},
+	{"type":"function","name":"execute","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"dest","type":"address"},
+		{"name":"value","type":"uint256"},
+		{"name":"func","type":"bytes"}
+	]},
+	{"type":"function","name":"getNonce","stateMutability":"view","outputs":[{"name":"","type":"uint256"}],"inputs":[
+		{"name":"sender","type":"address"},
+		{"name":"key","type":"uint192"}
+	]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","outputs":[{"name":"","type":"uint256"}],"inputs":[
+		{"name":"owner","type":"address"}
+	]},
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","outputs":[{"name":"","type":"bool"}],"inputs":[
+		{"name":"to","type":"address"},
+		{"name":"amount","type":"uint256"}
+	]}
+]`
+
+var contracts = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(accountABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Sweeper moves funds out of the SimpleAccounts owned by stealth addresses.
+type Sweeper struct {
+	backend   Backend
+	bundler   *Bundler
+	paymaster Paymaster
+
+	// EntryPoint is the EntryPoint contract the operations are for.
+	EntryPoint common.Address
+	// Factory is the SimpleAccountFactory that deploys the accounts.
+	Factory common.Address
+	// Salt selects which of the owner's accounts is used; payers must use the same one.
+	Salt *big.Int
+}
+
+// NewSweeper returns a sweeper that reads the chain through backend and submits to bundler.
+// If paymaster is nil, the accounts pay for their own gas.
+func NewSweeper(backend Backend, bundler *Bundler, paymaster Paymaster) *Sweeper {
+	return &Sweeper{
+		backend:    backend,
+		bundler:    bundler,
+		paymaster:  paymaster,
+		EntryPoint: EntryPoint,
+		Factory:    SimpleAccountFactory,
+		Salt:       new(big.Int),
+	}
+}
+
+// call runs the view method of the contract at addr and returns its single result.
+func call(ctx context.Context, caller bind.ContractCaller, addr common.Address, method string, args ...interface{}) (interface{}, error) {
+	data, err := contracts.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := caller.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := contracts.Unpack(method, out)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// AccountAddress returns the address of the account that factory deploys for owner and
+// salt, whether it is deployed yet or not. Payers send the funds for a stealth address
+// there, see sender.Sender.PayTo.
+func AccountAddress(ctx context.Context, caller bind.ContractCaller, factory, owner common.Address, salt *big.Int) (common.Address, error) {
+	addr, err := call(ctx, caller, factory, "getAddress", owner, salt)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return addr.(common.Address), nil
+}
+
+// Account returns the address of the account owned by owner, see AccountAddress.
+func (s *Sweeper) Account(ctx context.Context, owner common.Address) (common.Address, error) {
+	return AccountAddress(ctx, s.backend, s.Factory, owner, s.Salt)
+}
+
+// Build returns the signed user operation that makes owner's account call to with value
+// and data. It deploys the account if it has no code yet, takes the fees from the node,
+// has the bundler estimate the gas limits and then the paymaster, if any, sponsor the gas.
+func (s *Sweeper) Build(ctx context.Context, owner Owner, to common.Address, value *big.Int, data []byte) (*UserOperation, error) {
+	account, err := s.Account(ctx, owner.Address())
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := call(ctx, s.backend, s.EntryPoint, "getNonce", account, new(big.Int))
+	if err != nil {
+		return nil, err
+	}
+	callData, err := contracts.Pack("execute", to, value, data)
+	if err != nil {
+		return nil, err
+	}
+	op := &UserOperation{
+		Sender:    account,
+		Nonce:     nonce.(*big.Int),
+		CallData:  callData,
+		Signature: dummySignature,
+	}
+	code, err := s.backend.CodeAt(ctx, account, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		create, err := contracts.Pack("createAccount", owner.Address(), s.Salt)
+		if err != nil {
+			return nil, err
+		}
+		op.InitCode = append(s.Factory.Bytes(), create...)
+	}
+	fees, err := txutil.SuggestFees(ctx, s.backend)
+	if err != nil {
+		return nil, err
+	}
+	if fees.GasPrice != nil {
+		op.MaxFeePerGas, op.MaxPriorityFeePerGas = fees.GasPrice, fees.GasPrice
+	} else {
+		op.MaxFeePerGas, op.MaxPriorityFeePerGas = fees.GasFeeCap, fees.GasTipCap
+	}
+
+	// A verifying paymaster signs over the gas limits, so they are final before it sees op:
+	// the bundler estimates them first, and the paymaster's signed paymasterAndData is the
+	// last change before the owner signs.
+	if isZero(op.CallGasLimit) || isZero(op.VerificationGasLimit) || isZero(op.PreVerificationGas) {
+		if err := s.bundler.EstimateGas(ctx, op, s.EntryPoint); err != nil {
+			return nil, err
+		}
+	}
+	if s.paymaster != nil {
+		if err := s.paymaster.Sponsor(ctx, op, s.EntryPoint); err != nil {
+			return nil, err
+		}
+	}
+	chainID, err := s.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := op.Sign(owner, s.EntryPoint, chainID); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func isZero(x *big.Int) bool {
+	return x == nil || x.Sign() == 0
+}
+
+// Execute builds the user operation of Build and submits it, returning its hash. Use
+// Bundler.Wait to wait for its receipt.
+func (s *Sweeper) Execute(ctx context.Context, owner Owner, to common.Address, value *big.Int, data []byte) (common.Hash, error) {
+	op, err := s.Build(ctx, owner, to, value, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return s.bundler.Send(ctx, op, s.EntryPoint)
+}
+
+// SweepToken transfers the whole balance of the ERC-20 token held by owner's account to to.
+func (s *Sweeper) SweepToken(ctx context.Context, owner Owner, token, to common.Address) (common.Hash, error) {
+	account, err := s.Account(ctx, owner.Address())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	balance, err := call(ctx, s.backend, token, "balanceOf", account)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	amount := balance.(*big.Int)
+	if amount.Sign() == 0 {
+		return common.Hash{}, errors.New("account holds none of the token")
+	}
+	data, err := contracts.Pack("transfer", to, amount)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return s.Execute(ctx, owner, token, new(big.Int), data)
+}
+
+// SweepETH transfers the whole ETH balance of owner's account to to. It needs a paymaster:
+// without one the account would have to keep back ETH for gas.
+func (s *Sweeper) SweepETH(ctx context.Context, owner Owner, to common.Address) (common.Hash, error) {
+	if s.paymaster == nil {
+		return common.Hash{}, errors.New("sweeping all ETH needs a paymaster")
+	}
+	account, err := s.Account(ctx, owner.Address())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	balance, err := s.backend.BalanceAt(ctx, account, nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if balance.Sign() == 0 {
+		return common.Hash{}, errors.New("account holds no ETH")
+	}
+	return s.Execute(ctx, owner, to, balance, nil)
+}
@@ -0,0 +1,130 @@
+package userop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// fakeChain is a Backend on which owner's account is not deployed yet. Methods a sweep
+// does not use are left to the nil embedded Backend.
+type fakeChain struct {
+	Backend
+	account common.Address
+}
+
+func (c *fakeChain) CodeAt(ctx context.Context, addr common.Address, block *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *fakeChain) CallContract(ctx context.Context, call ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	method, err := contracts.MethodById(call.Data)
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "getAddress":
+		return method.Outputs.Pack(c.account)
+	case "getNonce":
+		return method.Outputs.Pack(big.NewInt(5))
+	}
+	return nil, errors.New("unexpected call of " + method.Name)
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(10e9)}, nil
+}
+
+func (c *fakeChain) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (c *fakeChain) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(11155111), nil
+}
+
+// fakeBundler serves eth_estimateUserOperationGas.
+type fakeBundler struct {
+	calls *[]string
+}
+
+func (b *fakeBundler) EstimateUserOperationGas(op userOperationJSON, entryPoint common.Address) (*gasEstimate, error) {
+	*b.calls = append(*b.calls, "estimate")
+	if len(op.PaymasterAndData) != 0 {
+		return nil, errors.New("estimating after the paymaster signed")
+	}
+	return &gasEstimate{
+		CallGasLimit:         (*hexutil.Big)(big.NewInt(60000)),
+		VerificationGasLimit: (*hexutil.Big)(big.NewInt(400000)),
+		PreVerificationGas:   (*hexutil.Big)(big.NewInt(50000)),
+	}, nil
+}
+
+// fakePaymaster is a verifying paymaster: its paymasterAndData commits to the gas limits
+// and fees it saw, like a signature over them would.
+type fakePaymaster struct {
+	calls *[]string
+}
+
+// commitment returns the paymasterAndData of op.
+func (p *fakePaymaster) commitment(op *UserOperation) []byte {
+	data := common.HexToAddress("0x00000f79b7faf42eebadba19acc07cd08af44789").Bytes()
+	for _, x := range []*big.Int{op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas, op.MaxFeePerGas, op.MaxPriorityFeePerGas} {
+		data = append(data, orZero(x).Bytes()...)
+	}
+	return data
+}
+
+func (p *fakePaymaster) Sponsor(ctx context.Context, op *UserOperation, entryPoint common.Address) error {
+	*p.calls = append(*p.calls, "sponsor")
+	if isZero(op.CallGasLimit) || isZero(op.VerificationGasLimit) || isZero(op.PreVerificationGas) {
+		return errors.New("sponsoring before the gas limits are set")
+	}
+	op.PaymasterAndData = p.commitment(op)
+	return nil
+}
+
+func TestBuildOrder(t *testing.T) {
+	var calls []string
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &fakeBundler{&calls}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	bundler := NewBundler(rpc.DialInProc(server))
+	defer bundler.Close()
+
+	owner := newKeyOwner(t)
+	owner.signed = func() { calls = append(calls, "sign") }
+	chain := &fakeChain{account: common.HexToAddress("0x2222222222222222222222222222222222222222")}
+	paymaster := &fakePaymaster{&calls}
+	sweeper := NewSweeper(chain, bundler, paymaster)
+
+	to := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	op, err := sweeper.Build(context.Background(), owner, to, big.NewInt(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"estimate", "sponsor", "sign"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls %v, want %v", calls, want)
+	}
+	if op.Sender != chain.account || op.Nonce.Int64() != 5 || len(op.InitCode) == 0 {
+		t.Fatalf("op for %s nonce %d with %d bytes of init code, want the undeployed account %s at nonce 5",
+			op.Sender, op.Nonce, len(op.InitCode), chain.account)
+	}
+	if !bytes.Equal(op.PaymasterAndData, paymaster.commitment(op)) {
+		t.Fatal("gas fields changed after the paymaster sponsored the operation")
+	}
+	chainID, _ := chain.ChainID(context.Background())
+	if got := recoverSigner(t, op, chainID); got != owner.Address() {
+		t.Fatalf("signature recovers to %s, want owner %s", got, owner.Address())
+	}
+}
@@ -0,0 +1,151 @@
+package userop
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"time"
+)
+
+// Bundler is a client of the ERC-4337 bundler RPC API.
+type Bundler struct {
+	client *rpc.Client
+}
+
+// DialBundler connects to the bundler RPC endpoint at url.
+func DialBundler(url string) (*Bundler, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewBundler(client), nil
+}
+
+// NewBundler returns a bundler client using client.
+func NewBundler(client *rpc.Client) *Bundler {
+	return &Bundler{client: client}
+}
+
+// Close closes the connection to the bundler.
+func (b *Bundler) Close() {
+	b.client.Close()
+}
+
+// gasEstimate is the result of eth_estimateUserOperationGas and the gas part of the result
+// of pm_sponsorUserOperation.
+type gasEstimate struct {
+	CallGasLimit         *hexutil.Big `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big `json:"preVerificationGas"`
+}
+
+// apply sets the gas limits of op that the estimate provides.
+func (g *gasEstimate) apply(op *UserOperation) {
+	if g.CallGasLimit != nil {
+		op.CallGasLimit = g.CallGasLimit.ToInt()
+	}
+	if g.VerificationGasLimit != nil {
+		op.VerificationGasLimit = g.VerificationGasLimit.ToInt()
+	}
+	if g.PreVerificationGas != nil {
+		op.PreVerificationGas = g.PreVerificationGas.ToInt()
+	}
+}
+
+// EstimateGas sets the three gas limits of op to the bundler's estimate. op needs a
+// signature of the right length, which does not have to be valid.
+func (b *Bundler) EstimateGas(ctx context.Context, op *UserOperation, entryPoint common.Address) error {
+	var est gasEstimate
+	if err := b.client.CallContext(ctx, &est, "eth_estimateUserOperationGas", op, entryPoint); err != nil {
+		return err
+	}
+	est.apply(op)
+	return nil
+}
+
+// Send submits the signed op to the bundler and returns its user operation hash.
+func (b *Bundler) Send(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	var hash common.Hash
+	err := b.client.CallContext(ctx, &hash, "eth_sendUserOperation", op, entryPoint)
+	return hash, err
+}
+
+// Receipt is the outcome of an included user operation.
+type Receipt struct {
+	Success bool `json:"success"`
+	// Reason is the revert reason if the call failed.
+	Reason        string       `json:"reason"`
+	ActualGasCost *hexutil.Big `json:"actualGasCost"`
+	Receipt       struct {
+		TransactionHash common.Hash `json:"transactionHash"`
+	} `json:"receipt"`
+}
+
+// Receipt returns the receipt of the user operation hash, or nil if it is not included yet.
+func (b *Bundler) Receipt(ctx context.Context, hash common.Hash) (*Receipt, error) {
+	var receipt *Receipt
+	err := b.client.CallContext(ctx, &receipt, "eth_getUserOperationReceipt", hash)
+	return receipt, err
+}
+
+// Wait polls the bundler until the user operation hash is included and returns its receipt.
+func (b *Bundler) Wait(ctx context.Context, hash common.Hash) (*Receipt, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		receipt, err := b.Receipt(ctx, hash)
+		if err != nil || receipt != nil {
+			return receipt, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Paymaster sponsors the gas of user operations.
+type Paymaster interface {
+	// Sponsor sets the paymasterAndData of op, and its gas limits if the paymaster
+	// estimates them. The sweeper has already set the gas limits and fees, and signs op
+	// afterwards, so the signature may be a dummy.
+	Sponsor(ctx context.Context, op *UserOperation, entryPoint common.Address) error
+}
+
+// RPCPaymaster is a paymaster behind the pm_sponsorUserOperation RPC method offered by
+// most bundler providers.
+type RPCPaymaster struct {
+	client *rpc.Client
+	// Context is passed as the third parameter of pm_sponsorUserOperation if it is not nil,
+	// for providers that take a sponsorship policy there.
+	Context interface{}
+}
+
+// NewRPCPaymaster returns the paymaster served by client, which is often the bundler's own
+// endpoint.
+func NewRPCPaymaster(client *rpc.Client) *RPCPaymaster {
+	return &RPCPaymaster{client: client}
+}
+
+// sponsorResult is the result of pm_sponsorUserOperation.
+type sponsorResult struct {
+	gasEstimate
+	PaymasterAndData hexutil.Bytes `json:"paymasterAndData"`
+}
+
+// Sponsor implements Paymaster.
+func (p *RPCPaymaster) Sponsor(ctx context.Context, op *UserOperation, entryPoint common.Address) error {
+	params := []interface{}{op, entryPoint}
+	if p.Context != nil {
+		params = append(params, p.Context)
+	}
+	var res sponsorResult
+	if err := p.client.CallContext(ctx, &res, "pm_sponsorUserOperation", params...); err != nil {
+		return err
+	}
+	res.apply(op)
+	op.PaymasterAndData = res.PaymasterAndData
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package userop sweeps stealth payments through ERC-4337 account abstraction, so that a
+// recipient can move tokens out of a stealth address that holds no ETH for gas.
+//
+// An externally owned stealth address cannot pay for its own transactions, and ERC-4337
+// cannot act for it either: the sender of a UserOperation is always a smart contract
+// account. The funds therefore have to be held by a counterfactual SimpleAccount whose
+// owner is the stealth address. Its address is known before it is deployed, so a payer
+// sends the funds there (see sender.Sender.PayTo) while announcing the stealth address as
+// usual. To sweep, the recipient signs a UserOperation with the stealth private key; the
+// EntryPoint deploys the account on first use, a paymaster sponsors the gas and a bundler
+// submits it.
+//
+// The package targets EntryPoint v0.6 and the eth-infinitism SimpleAccountFactory.
+package userop
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// EntryPoint is the address of the canonical EntryPoint v0.6 contract.
+var EntryPoint = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+
+// UserOperation is an ERC-4337 v0.6 user operation.
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// userOperationJSON is the encoding of a UserOperation in the bundler and paymaster RPC APIs.
+type userOperationJSON struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// MarshalJSON encodes op as the bundler RPC API expects, with unset numbers as zero.
+func (op *UserOperation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userOperationJSON{
+		Sender:               op.Sender,
+		Nonce:                hexBig(op.Nonce),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         hexBig(op.CallGasLimit),
+		VerificationGasLimit: hexBig(op.VerificationGasLimit),
+		PreVerificationGas:   hexBig(op.PreVerificationGas),
+		MaxFeePerGas:         hexBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: hexBig(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	})
+}
+
+func hexBig(x *big.Int) *hexutil.Big {
+	if x == nil {
+		return (*hexutil.Big)(new(big.Int))
+	}
+	return (*hexutil.Big)(x)
+}
+
+func orZero(x *big.Int) *big.Int {
+	if x == nil {
+		return new(big.Int)
+	}
+	return x
+}
+
+var (
+	addressType, _ = abi.NewType("address", "", nil)
+	uint256Type, _ = abi.NewType("uint256", "", nil)
+	bytes32Type, _ = abi.NewType("bytes32", "", nil)
+
+	// packedOpArgs is the abi.encode layout of UserOperationLib.pack in EntryPoint v0.6.
+	packedOpArgs = abi.Arguments{
+		{Type: addressType}, {Type: uint256Type}, {Type: bytes32Type}, {Type: bytes32Type},
+		{Type: uint256Type}, {Type: uint256Type}, {Type: uint256Type}, {Type: uint256Type},
+		{Type: uint256Type}, {Type: bytes32Type},
+	}
+	opHashArgs = abi.Arguments{{Type: bytes32Type}, {Type: addressType}, {Type: uint256Type}}
+)
+
+// Hash returns the hash the EntryPoint at entryPoint on chain chainID computes for op with
+// getUserOpHash. It covers every field but the signature.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) (common.Hash, error) {
+	packed, err := packedOpArgs.Pack(
+		op.Sender, orZero(op.Nonce),
+		crypto.Keccak256Hash(op.InitCode), crypto.Keccak256Hash(op.CallData),
+		orZero(op.CallGasLimit), orZero(op.VerificationGasLimit), orZero(op.PreVerificationGas),
+		orZero(op.MaxFeePerGas), orZero(op.MaxPriorityFeePerGas),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	enc, err := opHashArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// Owner signs for a smart account. *signer.StealthSigner implements it, so the stealth
+// private key never leaves the signer.
+type Owner interface {
+	// Address returns the owner address of the account.
+	Address() common.Address
+	// SignHash returns the 65-byte crypto.Sign signature of a 32-byte hash.
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// Sign sets the signature of op for the EntryPoint at entryPoint on chain chainID. A
+// SimpleAccount checks it against its owner after applying the eth_sign prefix to the hash,
+// and expects V to be 27 or 28.
+func (op *UserOperation) Sign(owner Owner, entryPoint common.Address, chainID *big.Int) error {
+	hash, err := op.Hash(entryPoint, chainID)
+	if err != nil {
+		return err
+	}
+	sig, err := owner.SignHash(accounts.TextHash(hash.Bytes()))
+	if err != nil {
+		return err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	op.Signature = sig
+	return nil
+}
+
+// dummySignature stands in for the signature while the gas is estimated: it has the right
+// length and recovers to some address, so the account's validation runs to the end.
+var dummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
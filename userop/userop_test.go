@@ -0,0 +1,125 @@
+package userop
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+// TestHash checks Hash against getUserOpHash of EntryPoint v0.6,
+// keccak256(abi.encode(keccak256(pack(op)), entryPoint, chainId)), computed independently
+// of this package with a from-scratch Python Keccak-256.
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      *UserOperation
+		chainID int64
+		want    string
+	}{
+		{
+			name: "all fields",
+			op: &UserOperation{
+				Sender:               common.HexToAddress("0x1111111111111111111111111111111111111111"),
+				Nonce:                big.NewInt(7),
+				InitCode:             hexutil.MustDecode("0x9406cc6185a346906296840746125a0e449764545fbfb9cf"),
+				CallData:             hexutil.MustDecode("0xb61d27f6"),
+				CallGasLimit:         big.NewInt(100000),
+				VerificationGasLimit: big.NewInt(200000),
+				PreVerificationGas:   big.NewInt(50000),
+				MaxFeePerGas:         big.NewInt(30e9),
+				MaxPriorityFeePerGas: big.NewInt(2e9),
+				PaymasterAndData:     hexutil.MustDecode("0x00000f79b7faf42eebadba19acc07cd08af44789"),
+				Signature:            hexutil.MustDecode("0x01"),
+			},
+			chainID: 11155111,
+			want:    "0x7cc81e7c32161bcfdfdc33fe4d3998b146388370170cee4a461886ad11637558",
+		},
+		{
+			name:    "unset fields",
+			op:      &UserOperation{Sender: common.HexToAddress("0x1111111111111111111111111111111111111111")},
+			chainID: 1,
+			want:    "0x45701cfd21ab570f3f2647b8d9226eb0710ef1e9b72f9f49d35ac3f2a462ae8c",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.op.Hash(EntryPoint, big.NewInt(tt.chainID))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Hex() != tt.want {
+			t.Errorf("%s: hash %s, want %s", tt.name, got.Hex(), tt.want)
+		}
+	}
+}
+
+// keyOwner is an Owner signing with a private key in memory.
+type keyOwner struct {
+	key *ecdsa.PrivateKey
+	// signed, if set, is called on every SignHash, for tests that check when signing happens.
+	signed func()
+}
+
+func (o *keyOwner) Address() common.Address {
+	return crypto.PubkeyToAddress(o.key.PublicKey)
+}
+
+func (o *keyOwner) SignHash(hash []byte) ([]byte, error) {
+	if o.signed != nil {
+		o.signed()
+	}
+	return crypto.Sign(hash, o.key)
+}
+
+func newKeyOwner(t *testing.T) *keyOwner {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keyOwner{key: key}
+}
+
+// recoverSigner returns the address a SimpleAccount recovers from the signature of op.
+func recoverSigner(t *testing.T, op *UserOperation, chainID *big.Int) common.Address {
+	t.Helper()
+	hash, err := op.Hash(EntryPoint, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Signature) != 65 || (op.Signature[64] != 27 && op.Signature[64] != 28) {
+		t.Fatalf("signature %x is not 65 bytes with V 27 or 28", op.Signature)
+	}
+	sig := append([]byte(nil), op.Signature...)
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+func TestSign(t *testing.T) {
+	owner := newKeyOwner(t)
+	op := &UserOperation{
+		Sender:       common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Nonce:        big.NewInt(3),
+		CallData:     hexutil.MustDecode("0xb61d27f6"),
+		CallGasLimit: big.NewInt(100000),
+		Signature:    dummySignature,
+	}
+	chainID := big.NewInt(11155111)
+	if err := op.Sign(owner, EntryPoint, chainID); err != nil {
+		t.Fatal(err)
+	}
+	if got := recoverSigner(t, op, chainID); got != owner.Address() {
+		t.Fatalf("signature recovers to %s, want owner %s", got, owner.Address())
+	}
+	// The signature is for this chain and EntryPoint only.
+	if got := recoverSigner(t, op, big.NewInt(1)); got == owner.Address() {
+		t.Fatal("signature also recovers to the owner on another chain")
+	}
+}
@@ -0,0 +1,117 @@
+#!/usr/bin/env python3
+"""Generates generated.json, the known-answer vectors of package vectors.
+
+The vectors are computed with the from-scratch Keccak-256 and secp256k1 below, written for
+the purpose and sharing no code with the Go module, so that a bug in the Go implementation
+cannot reproduce itself in the expected values. They are not the EIP's published vectors.
+
+Run it with go generate ./vectors, or as python3 generate.py [count].
+"""
+import json
+import sys
+
+# --- Keccak-256 (original padding 0x01) ---
+RC = [0x0000000000000001,0x0000000000008082,0x800000000000808A,0x8000000080008000,0x000000000000808B,0x0000000080000001,
+0x8000000080008081,0x8000000000008009,0x000000000000008A,0x0000000000000088,0x0000000080008009,0x000000008000000A,
+0x000000008000808B,0x800000000000008B,0x8000000000008089,0x8000000000008003,0x8000000000008002,0x8000000000000080,
+0x000000000000800A,0x800000008000000A,0x8000000080008081,0x8000000000008080,0x0000000080000001,0x8000000080008008]
+ROT = [[0,36,3,41,18],[1,44,10,45,2],[62,6,43,15,61],[28,55,25,21,56],[27,20,39,8,14]]
+M64 = (1<<64)-1
+def rol(x,n): return ((x<<n)|(x>>(64-n)))&M64 if n else x
+def f(A):
+    for rc in RC:
+        C=[A[x][0]^A[x][1]^A[x][2]^A[x][3]^A[x][4] for x in range(5)]
+        D=[C[(x-1)%5]^rol(C[(x+1)%5],1) for x in range(5)]
+        A=[[A[x][y]^D[x] for y in range(5)] for x in range(5)]
+        B=[[0]*5 for _ in range(5)]
+        for x in range(5):
+            for y in range(5):
+                B[y][(2*x+3*y)%5]=rol(A[x][y],ROT[x][y])
+        A=[[B[x][y]^((~B[(x+1)%5][y])&B[(x+2)%5][y]) for y in range(5)] for x in range(5)]
+        A[0][0]^=rc
+    return A
+def keccak256(data):
+    rate=136
+    data=bytearray(data)+b'\x01'
+    while len(data)%rate: data+=b'\x00'
+    data[-1]|=0x80
+    A=[[0]*5 for _ in range(5)]
+    for off in range(0,len(data),rate):
+        blk=data[off:off+rate]
+        for i in range(rate//8):
+            x,y=i%5,i//5
+            A[x][y]^=int.from_bytes(blk[8*i:8*i+8],'little')
+        A=f(A)
+    out=b''
+    for i in range(4):
+        x,y=i%5,i//5
+        out+=A[x][y].to_bytes(8,'little')
+    return out
+assert keccak256(b'').hex()=='c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470'
+
+# --- secp256k1 ---
+P=2**256-2**32-977
+N=0xFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141
+G=(0x79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798,0x483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8)
+def add(p,q):
+    if p is None: return q
+    if q is None: return p
+    if p[0]==q[0] and (p[1]+q[1])%P==0: return None
+    if p==q: l=3*p[0]*p[0]*pow(2*p[1],-1,P)%P
+    else: l=(q[1]-p[1])*pow(q[0]-p[0],-1,P)%P
+    x=(l*l-p[0]-q[0])%P
+    return (x,(l*(p[0]-x)-p[1])%P)
+def mul(k,p):
+    r=None
+    while k:
+        if k&1: r=add(r,p)
+        p=add(p,p); k>>=1
+    return r
+def comp(p): return bytes([2+(p[1]&1)])+p[0].to_bytes(32,'big')
+def addr(p): return keccak256(p[0].to_bytes(32,'big')+p[1].to_bytes(32,'big'))[12:]
+assert addr(mul(1,G)).hex()=='7e5f4552091a69125d5dfcb7b8c2659029395bdf'
+
+
+SOURCE = ("Generated by vectors/generate.py with an independent Python implementation of secp256k1 and Keccak-256, "
+          "not with this module. Keys are keccak256(\"eip5564 vector <i> spending|viewing|ephemeral\") mod N.")
+
+
+def key(label):
+    return int.from_bytes(keccak256(label.encode()), 'big') % N
+
+
+def vector(i):
+    m = key(f"eip5564 vector {i} spending")
+    v = key(f"eip5564 vector {i} viewing")
+    r = key(f"eip5564 vector {i} ephemeral")
+    M = mul(m, G); V = mul(v, G); R = mul(r, G)
+    S = mul(r, V)
+    assert S == mul(v, R)
+    h = keccak256(comp(S)); hs = int.from_bytes(h, 'big') % N
+    Pk = add(M, mul(hs, G)); p = (m + hs) % N
+    assert mul(p, G) == Pk
+    return {
+        "name": f"generated-{i}",
+        "spendingPrivateKey": "0x%064x" % m,
+        "viewingPrivateKey": "0x%064x" % v,
+        "stealthMetaAddress": "st:eth:0x" + (comp(M) + comp(V)).hex(),
+        "ephemeralPrivateKey": "0x%064x" % r,
+        "ephemeralPublicKey": "0x" + comp(R).hex(),
+        "stealthAddress": "0x" + addr(Pk).hex(),
+        "viewTag": "0x%02x" % h[0],
+        "stealthPrivateKey": "0x%064x" % p,
+    }
+
+
+def main():
+    count = int(sys.argv[1]) if len(sys.argv) > 1 else 8
+    doc = {
+        "source": SOURCE,
+        "vectors": [vector(i) for i in range(count)],
+    }
+    with open('generated.json', 'w') as f:
+        f.write(json.dumps(doc, indent=2) + "\n")
+
+
+if __name__ == '__main__':
+    main()
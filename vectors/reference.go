@@ -0,0 +1,50 @@
+package vectors
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ReferenceVersion is bumped whenever the layout of Reference or the derivation changes.
+const ReferenceVersion = 5
+
+//go:embed reference.json
+var reference []byte
+
+// Reference is the known-answer vector of the stealth demo: its hardcoded inputs m, v and r
+// and every value derived from them, keyed by the symbols used in the walkthrough.
+type Reference struct {
+	Version             int            `json:"version"`
+	SpendingPrivateKey  hexutil.Bytes  `json:"m"`
+	SpendingPublicKey   hexutil.Bytes  `json:"M"`
+	ViewingPrivateKey   hexutil.Bytes  `json:"v"`
+	ViewingPublicKey    hexutil.Bytes  `json:"V"`
+	MetaAddress         string         `json:"metaAddress"`
+	EphemeralPrivateKey hexutil.Bytes  `json:"r"`
+	EphemeralPublicKey  hexutil.Bytes  `json:"R"`
+	SharedSecret        hexutil.Bytes  `json:"S"`
+	HashS               hexutil.Bytes  `json:"hashS"`
+	StealthPublicKey    hexutil.Bytes  `json:"P"`
+	StealthAddress      common.Address `json:"address"`
+	ViewTag             hexutil.Bytes  `json:"viewTag"`
+	StealthPrivateKey   hexutil.Bytes  `json:"p"`
+}
+
+// ReferenceVector returns the demo's known-answer vector as canonical JSON, the encoding
+// of Encode. It is the file vectors/reference.json, so that other implementations can
+// check their outputs against it field by field.
+func ReferenceVector() []byte {
+	return append([]byte(nil), reference...)
+}
+
+// Encode returns the canonical JSON of r: an object indented by two spaces with the fields
+// in the order of Reference, followed by a newline.
+func (r *Reference) Encode() ([]byte, error) {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,76 @@
+package vectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"stealth/stealthaddr"
+	"testing"
+)
+
+// TestReferenceRoundTrip decodes the reference vector and checks that Encode gives back the
+// same bytes, so the checked-in file is canonical.
+func TestReferenceRoundTrip(t *testing.T) {
+	var ref Reference
+	if err := json.Unmarshal(ReferenceVector(), &ref); err != nil {
+		t.Fatal(err)
+	}
+	if ref.Version != ReferenceVersion {
+		t.Fatalf("reference vector has version %d, want %d", ref.Version, ReferenceVersion)
+	}
+	out, err := ref.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, ReferenceVector()) {
+		t.Fatalf("re-encoded vector differs:\n%s", out)
+	}
+}
+
+// TestReferenceMatchesDerivation recomputes every value of the reference vector from its
+// inputs m, v and r.
+func TestReferenceMatchesDerivation(t *testing.T) {
+	var ref Reference
+	if err := json.Unmarshal(ReferenceVector(), &ref); err != nil {
+		t.Fatal(err)
+	}
+	m := new(big.Int).SetBytes(ref.SpendingPrivateKey)
+	v := new(big.Int).SetBytes(ref.ViewingPrivateKey)
+	bob := &stealthaddr.Account{SpendingKey: m, ViewingKey: v}
+	r, err := stealthaddr.NewEphemeralKey(new(big.Int).SetBytes(ref.EphemeralPrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := stealthaddr.ComputeStealthAddress(bob.MetaAddress(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	S := stealthaddr.SharedSecret(v, r.PublicKey)
+	hashS, err := stealthaddr.SharedSecretToScalar(S)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := stealthaddr.DeriveStealthPrivateKey(m, v, r.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := ref
+	live.SpendingPublicKey = bob.MetaAddress().SpendingPubKey.Bytes()
+	live.ViewingPublicKey = bob.MetaAddress().ViewingPubKey.Bytes()
+	live.MetaAddress = bob.MetaAddress().String()
+	live.EphemeralPublicKey = r.PublicKey.Bytes()
+	live.SharedSecret = S.Bytes()
+	live.HashS = hashS.Bytes()
+	live.StealthPublicKey = payment.PublicKey.Bytes()
+	live.StealthAddress = payment.Address
+	live.ViewTag = []byte{payment.ViewTag}
+	live.StealthPrivateKey = p.Bytes()
+	got, err := live.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, ReferenceVector()) {
+		t.Fatalf("derivation gives\n%s\nreference vector is\n%s", got, ReferenceVector())
+	}
+}
@@ -0,0 +1,157 @@
+// Package vectors checks the stealthaddr implementation of ERC-5564 scheme 1 against
+// known-answer test vectors in a JSON format that other implementations can produce too.
+//
+// The embedded set is not the EIP's own: generate.py computes it with an independent
+// implementation of secp256k1 and Keccak-256 written for the purpose, not with this module,
+// so a bug in the Go code does not hide itself. "go generate" rewrites generated.json. Vectors exported from other
+// implementations in the same format can be checked with Parse and Verify, for example by
+// the verify-vectors command.
+//
+// ReferenceVector is a second, smaller artifact: the demo's single known-answer vector with
+// every intermediate value, for implementations that want to compare step by step.
+package vectors
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"stealth/stealthaddr"
+)
+
+//go:generate python3 generate.py
+
+//go:embed generated.json
+var embedded []byte
+
+// File is the JSON layout of a set of vectors.
+type File struct {
+	// Source says where the vectors come from.
+	Source  string   `json:"source"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vector is one payment: the recipient's meta-address, the sender's ephemeral key and what
+// both sides must derive from them. The private keys of the recipient are optional; without
+// them only the sender's side is checked.
+type Vector struct {
+	Name                string         `json:"name"`
+	SpendingPrivateKey  hexutil.Bytes  `json:"spendingPrivateKey,omitempty"`
+	ViewingPrivateKey   hexutil.Bytes  `json:"viewingPrivateKey,omitempty"`
+	StealthMetaAddress  string         `json:"stealthMetaAddress"`
+	EphemeralPrivateKey hexutil.Bytes  `json:"ephemeralPrivateKey"`
+	EphemeralPublicKey  hexutil.Bytes  `json:"ephemeralPublicKey"`
+	StealthAddress      common.Address `json:"stealthAddress"`
+	ViewTag             hexutil.Bytes  `json:"viewTag"`
+	StealthPrivateKey   hexutil.Bytes  `json:"stealthPrivateKey,omitempty"`
+}
+
+// Mismatch is a value the implementation derived differently from the vector, or an input
+// of the vector it rejected.
+type Mismatch struct {
+	Vector string
+	Field  string
+	Got    string
+	Want   string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s: got %s, want %s", m.Vector, m.Field, m.Got, m.Want)
+}
+
+// Embedded returns the generated vectors built into the package.
+func Embedded() (*File, error) {
+	return Parse(embedded)
+}
+
+// Parse decodes a set of vectors.
+func Parse(data []byte) (*File, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Vectors) == 0 {
+		return nil, errors.New("no vectors")
+	}
+	return &f, nil
+}
+
+// Verify runs every vector of f and returns the mismatches; none means all of them pass.
+func (f *File) Verify() []Mismatch {
+	var mismatches []Mismatch
+	for _, v := range f.Vectors {
+		mismatches = append(mismatches, v.Verify()...)
+	}
+	return mismatches
+}
+
+// Verify derives the payment of v on the sender's side and, if v has the recipient's keys,
+// on the recipient's side, and returns every value that differs from v.
+func (v *Vector) Verify() []Mismatch {
+	var mismatches []Mismatch
+	check := func(field string, got, want interface{}) {
+		if g, w := fmt.Sprint(got), fmt.Sprint(want); g != w {
+			mismatches = append(mismatches, Mismatch{Vector: v.Name, Field: field, Got: g, Want: w})
+		}
+	}
+	fail := func(field string, err error) []Mismatch {
+		return append(mismatches, Mismatch{Vector: v.Name, Field: field, Got: "error: " + err.Error(), Want: "success"})
+	}
+	hexPoint := func(p stealthaddr.Point) string { return hexutil.Encode(p.Bytes()) }
+
+	meta, err := stealthaddr.ParseMetaAddress(v.StealthMetaAddress)
+	if err != nil {
+		return fail("stealthMetaAddress", err)
+	}
+	r, err := stealthaddr.NewEphemeralKey(new(big.Int).SetBytes(v.EphemeralPrivateKey))
+	if err != nil {
+		return fail("ephemeralPrivateKey", err)
+	}
+	check("ephemeralPublicKey", hexPoint(r.PublicKey), v.EphemeralPublicKey)
+	payment, err := stealthaddr.ComputeStealthAddress(meta, r)
+	if err != nil {
+		return fail("stealthAddress", err)
+	}
+	check("stealthAddress", payment.Address, v.StealthAddress)
+	check("viewTag", hexutil.Encode([]byte{payment.ViewTag}), v.ViewTag)
+
+	if len(v.ViewingPrivateKey) == 0 {
+		return mismatches
+	}
+	viewingKey := new(big.Int).SetBytes(v.ViewingPrivateKey)
+	check("viewingPublicKey", hexPoint(stealthaddr.PublicKey(viewingKey)), hexPoint(meta.ViewingPubKey))
+	// The recipient scans the announcement of the vector, not the one derived above, so
+	// that each side is checked on its own.
+	announcement := &stealthaddr.Announcement{
+		SchemeID:        big.NewInt(stealthaddr.SchemeIDSecp256k1),
+		StealthAddress:  v.StealthAddress,
+		EphemeralPubKey: v.EphemeralPublicKey,
+		Metadata:        v.ViewTag,
+	}
+	found, ok, err := stealthaddr.CheckAnnouncement(viewingKey, meta.SpendingPubKey, announcement)
+	if err != nil {
+		return fail("recipient scan", err)
+	}
+	if !ok {
+		return append(mismatches, Mismatch{Vector: v.Name, Field: "recipient scan", Got: "no match", Want: "match"})
+	}
+	check("recipient stealthAddress", found.Address, v.StealthAddress)
+
+	if len(v.SpendingPrivateKey) == 0 {
+		return mismatches
+	}
+	spendingKey := new(big.Int).SetBytes(v.SpendingPrivateKey)
+	check("spendingPublicKey", hexPoint(stealthaddr.PublicKey(spendingKey)), hexPoint(meta.SpendingPubKey))
+	p, err := stealthaddr.DeriveStealthPrivateKey(spendingKey, viewingKey, found.EphemeralPublicKey)
+	if err != nil {
+		return fail("stealthPrivateKey", err)
+	}
+	if len(v.StealthPrivateKey) > 0 {
+		check("stealthPrivateKey", hexutil.Encode(common.LeftPadBytes(p.Bytes(), 32)), v.StealthPrivateKey)
+	}
+	check("address of stealthPrivateKey", stealthaddr.PublicKey(p).Address(), v.StealthAddress)
+	return mismatches
+}
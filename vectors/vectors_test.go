@@ -0,0 +1,33 @@
+package vectors
+
+import (
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	f, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range f.Verify() {
+		t.Error(m)
+	}
+}
+
+// TestVerifyReportsMismatch checks that a wrong vector fails, so that TestEmbedded passing
+// means something.
+func TestVerifyReportsMismatch(t *testing.T) {
+	f, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := f.Vectors[0]
+	v.ViewTag = []byte{v.ViewTag[0] ^ 1}
+	mismatches := v.Verify()
+	if len(mismatches) == 0 {
+		t.Fatal("a wrong view tag passes")
+	}
+	if mismatches[0].Field != "viewTag" {
+		t.Fatalf("first mismatch is %s, want viewTag", mismatches[0])
+	}
+}